@@ -0,0 +1,63 @@
+// Audiocheck verifies that every audio prompt referenced by i18n messages exists,
+// including messages overridden in config.yaml for the hosting organization and its tenants.
+// Prompts hosted elsewhere are only requested with -remote, keeping checks offline by default.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/audio"
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/i18n"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	remote := flag.Bool("remote", false, "also request prompts hosted at URLs")
+	flag.Parse()
+
+	conf, err := config.Load(true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	configs := map[string]config.Config{"": conf}
+	for _, tenant := range conf.Tenants {
+		configs[tenant.ID] = conf.ForTenant(tenant)
+	}
+
+	library := audio.Library{FS: audio.Assets()}
+	client := &http.Client{Timeout: 10 * time.Second} //nolint:exhaustruct,mnd
+
+	missing := 0
+	for tenant, conf := range configs {
+		mp, err := i18n.NewMessageProvider(slog.Default(), conf, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for lang, assets := range mp.AudioAssets() {
+			for _, asset := range assets {
+				if audio.IsURL(asset) && !*remote {
+					continue
+				}
+				err := library.Check(context.Background(), client, lang, asset)
+				if err != nil {
+					log.Printf("tenant %q, lang %s: %v", tenant, lang, err)
+					missing++
+				}
+			}
+		}
+	}
+
+	if missing > 0 {
+		log.Fatalf("%d audio prompts are missing", missing)
+	}
+	log.Print("All audio prompts exist")
+}
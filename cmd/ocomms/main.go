@@ -2,12 +2,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata" // the distroless runtime image doesn't include time zone data
 
 	"github.com/infotecho/ocomms/internal/app"
 	"github.com/infotecho/ocomms/internal/config"
 	"github.com/infotecho/ocomms/internal/log"
+	"github.com/infotecho/ocomms/internal/tracing"
 )
 
 func main() {
@@ -21,10 +28,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	srv := app.Server(conf, logger)
+	shutdownTracing, err := tracing.Setup(context.Background(), conf)
+	if err != nil {
+		logger.Error("Failed to set up tracing", "err", err)
+		os.Exit(1)
+	}
+
+	// Cloud Run sends SIGTERM before stopping an instance, e.g. when scaling down
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	serverFactory := app.WireDependencies(conf, logger)
+	srv := serverFactory.Server()
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		logger.Error("Failed to listen for HTTP", "err", err)
+		os.Exit(1) //nolint:gocritic // nothing to clean up if the server never served
+	}
+
+	go func() {
+		<-ctx.Done()
+		stop() // a second signal kills the server right away
+	}()
 
-	logger.Info("Listening and serving HTTP", "addr", srv.Addr)
-	err = srv.ListenAndServe()
-	logger.Error("Failed to listen and serve HTTP", "err", err)
-	os.Exit(1)
+	// spans are flushed first, as posting audit events left to a slow webhook may take all the time left
+	flush := func(ctx context.Context) error {
+		return errors.Join(shutdownTracing(ctx), serverFactory.Close(ctx))
+	}
+	err = app.Serve(ctx, &srv, listener, logger, conf.Server.Timeouts.ShutdownTimeout, flush)
+	if err != nil {
+		logger.Error("Failed to serve", "err", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,94 @@
+// Package agents looks up agents in the roster from application config.
+package agents
+
+import (
+	"slices"
+
+	"github.com/infotecho/ocomms/internal/config"
+)
+
+// ByDID returns the agent who owns phone number did, and whether one was found.
+func ByDID(roster []config.Agent, did string) (config.Agent, bool) {
+	if did == "" {
+		return config.Agent{}, false //nolint:exhaustruct
+	}
+
+	for _, agent := range roster {
+		if slices.Contains(agent.PhoneNumbers(), did) {
+			return agent, true
+		}
+	}
+
+	return config.Agent{}, false //nolint:exhaustruct
+}
+
+// ByID returns the agent with the given ID, and whether one was found.
+func ByID(roster []config.Agent, id string) (config.Agent, bool) {
+	i := slices.IndexFunc(roster, func(agent config.Agent) bool { return agent.ID == id })
+	if id == "" || i < 0 {
+		return config.Agent{}, false //nolint:exhaustruct
+	}
+
+	return roster[i], true
+}
+
+// PhoneNumbers returns the phone numbers of every agent in roster.
+func PhoneNumbers(roster []config.Agent) []string {
+	var numbers []string
+	for _, agent := range roster {
+		numbers = append(numbers, agent.PhoneNumbers()...)
+	}
+
+	return numbers
+}
+
+// Speaking returns the agents in roster who speak lang.
+func Speaking(roster []config.Agent, lang string) []config.Agent {
+	return filter(roster, func(a config.Agent) bool { return slices.Contains(a.Languages, lang) })
+}
+
+// WithSkill returns the agents in roster who have skill.
+func WithSkill(roster []config.Agent, skill string) []config.Agent {
+	return filter(roster, func(a config.Agent) bool { return slices.Contains(a.Skills, skill) })
+}
+
+// RingGroups returns the groups of agents to ring in turn for a caller speaking lang who needs skill,
+// from the best match to everyone in roster:
+// agents with the skill who speak the language, agents with the skill, agents who speak the language, then everyone.
+// Empty groups and groups identical to the previous one are skipped. skill may be empty if no skill is needed.
+func RingGroups(roster []config.Agent, lang string, skill string) [][]config.Agent {
+	var candidates [][]config.Agent
+	if skill != "" {
+		skilled := WithSkill(roster, skill)
+		candidates = append(candidates, Speaking(skilled, lang), skilled)
+	}
+	candidates = append(candidates, Speaking(roster, lang), roster)
+
+	var groups [][]config.Agent
+	for _, group := range candidates {
+		if len(group) == 0 {
+			continue
+		}
+		if len(groups) > 0 && sameAgents(groups[len(groups)-1], group) {
+			continue
+		}
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+func filter(roster []config.Agent, keep func(config.Agent) bool) []config.Agent {
+	var agents []config.Agent
+	for _, agent := range roster {
+		if keep(agent) {
+			agents = append(agents, agent)
+		}
+	}
+
+	return agents
+}
+
+func sameAgents(a []config.Agent, b []config.Agent) bool {
+	return slices.EqualFunc(a, b, func(x config.Agent, y config.Agent) bool { return x.ID == y.ID })
+}
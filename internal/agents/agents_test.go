@@ -0,0 +1,87 @@
+package agents_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/infotecho/ocomms/internal/agents"
+	"github.com/infotecho/ocomms/internal/config"
+)
+
+func agent(id string, languages []string, skills []string) config.Agent {
+	return config.Agent{ID: id, Languages: languages, Skills: skills} //nolint:exhaustruct
+}
+
+func TestRingGroups(t *testing.T) {
+	t.Parallel()
+
+	roster := []config.Agent{
+		agent("bilingual-support", []string{"en", "fr"}, []string{"support"}),
+		agent("english-sales", []string{"en"}, []string{"sales"}),
+		agent("french-sales", []string{"fr"}, []string{"sales"}),
+	}
+
+	tests := []struct {
+		name  string
+		lang  string
+		skill string
+		want  [][]string
+	}{
+		{
+			name: "english",
+			lang: "en",
+			want: [][]string{{"bilingual-support", "english-sales"}, {"bilingual-support", "english-sales", "french-sales"}},
+		},
+		{
+			name: "french",
+			lang: "fr",
+			want: [][]string{{"bilingual-support", "french-sales"}, {"bilingual-support", "english-sales", "french-sales"}},
+		},
+		{
+			name:  "english sales",
+			lang:  "en",
+			skill: "sales",
+			want: [][]string{
+				{"english-sales"},
+				{"english-sales", "french-sales"},
+				{"bilingual-support", "english-sales"},
+				{"bilingual-support", "english-sales", "french-sales"},
+			},
+		},
+		{
+			name:  "french support",
+			lang:  "fr",
+			skill: "support",
+			want: [][]string{
+				{"bilingual-support"},
+				{"bilingual-support", "french-sales"},
+				{"bilingual-support", "english-sales", "french-sales"},
+			},
+		},
+		{
+			name:  "unknown skill and language",
+			lang:  "es",
+			skill: "billing",
+			want:  [][]string{{"bilingual-support", "english-sales", "french-sales"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got [][]string
+			for _, group := range agents.RingGroups(roster, test.lang, test.skill) {
+				var ids []string
+				for _, agent := range group {
+					ids = append(ids, agent.ID)
+				}
+				got = append(got, ids)
+			}
+
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
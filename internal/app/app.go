@@ -3,27 +3,72 @@ package app
 
 import (
 	"log/slog"
-	"net/http"
 
+	"github.com/infotecho/ocomms/internal/audio"
+	"github.com/infotecho/ocomms/internal/audit"
+	"github.com/infotecho/ocomms/internal/callback"
+	"github.com/infotecho/ocomms/internal/callers"
+	"github.com/infotecho/ocomms/internal/conference"
 	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/export"
 	"github.com/infotecho/ocomms/internal/handler"
+	"github.com/infotecho/ocomms/internal/history"
 	"github.com/infotecho/ocomms/internal/i18n"
 	"github.com/infotecho/ocomms/internal/mail"
+	"github.com/infotecho/ocomms/internal/metrics"
+	"github.com/infotecho/ocomms/internal/presence"
+	"github.com/infotecho/ocomms/internal/screening"
+	"github.com/infotecho/ocomms/internal/store"
 	"github.com/infotecho/ocomms/internal/twigen"
+	"github.com/infotecho/ocomms/internal/voicemail"
 	"github.com/sendgrid/sendgrid-go"
+	"github.com/twilio/twilio-go"
 	"github.com/twilio/twilio-go/client"
 )
 
-// Server returns the [http.Server] implementing the O-Comms API.
-func Server(conf config.Config, logger *slog.Logger) http.Server {
-	app := WireDependencies(conf, logger)
+// WireDependencies handles dependency injection.
+func WireDependencies(config config.Config, logger *slog.Logger) ServerFactory {
+	storage, err := store.New(config)
+	if err != nil {
+		logger.Error("Failed to create storage", "err", err)
+		panic(err)
+	}
+	stats := metrics.New() // shared by tenants, so one scrape covers the deployment
 
-	return app.Server()
+	tenants := make([]Tenant, len(config.Tenants))
+	for i, tenant := range config.Tenants {
+		if tenant.ID == "" {
+			logger.Error("Tenants must have an ID to namespace their storage")
+			panic("missing tenant ID")
+		}
+		tenants[i] = Tenant{
+			Config: tenant,
+			MuxFactory: wireMux(
+				config.ForTenant(tenant),
+				logger.With("tenant", tenant.ID),
+				store.Prefixed{Prefix: "tenants/" + tenant.ID + "/", Store: storage},
+				stats,
+			),
+		}
+	}
+
+	return ServerFactory{
+		Config:     config,
+		Logger:     logger,
+		Metrics:    stats,
+		MuxFactory: wireMux(config, logger, storage, stats),
+		Tenants:    tenants,
+	}
 }
 
-// WireDependencies handles dependency injection.
-func WireDependencies(config config.Config, logger *slog.Logger) ServerFactory {
-	i18n, err := i18n.NewMessageProvider(logger, config)
+// wireMux creates the handlers for one organization's Twilio webhooks.
+func wireMux(
+	config config.Config,
+	logger *slog.Logger,
+	store store.Store,
+	metrics *metrics.Metrics,
+) *handler.MuxFactory {
+	i18n, err := i18n.NewMessageProvider(logger, config, metrics)
 	if err != nil {
 		logger.Error("Failed to load i18n messages", "err", err)
 		panic(err)
@@ -33,40 +78,133 @@ func WireDependencies(config config.Config, logger *slog.Logger) ServerFactory {
 		Config:         config,
 		I18n:           i18n,
 		Logger:         logger,
+		Metrics:        metrics,
 		SendGridClient: sendgrid.NewSendClient(config.Mail.SendGrid.APIKey),
 	}
 
+	twilioClient := twilio.NewRestClientWithParams(twilio.ClientParams{
+		Username: config.Twilio.AccountSID,
+		Password: config.Twilio.AuthToken,
+	})
+
 	requestValidator := client.NewRequestValidator(config.Twilio.AuthToken)
 	handlerFactory := &handler.TwimlHandlerFactory{
 		Logger:           logger,
+		Metrics:          metrics,
 		RequestValidator: &requestValidator,
 	}
 
-	return ServerFactory{
-		Config: config,
-		Logger: logger,
-		MuxFactory: &handler.MuxFactory{
-			Recordings: &handler.RecordingsHandler{
+	audioLibrary := &audio.Library{
+		FS: audio.Assets(),
+	}
+
+	schedule := &callback.Schedule{
+		Store: store,
+	}
+
+	trail := audit.NewTrail(config, logger, store)
+
+	callHistory := &history.Log{
+		Store:     store,
+		Retention: config.History.Retention,
+	}
+
+	voicemails := &voicemail.Box{
+		Recordings: twilioClient.Api,
+		Store:      store,
+	}
+
+	return &handler.MuxFactory{
+		Audit: &handler.AuditHandler{
+			Config: config,
+			Logger: logger,
+			Trail:  trail,
+		},
+		Audio:   audioLibrary,
+		Metrics: metrics,
+		Callbacks: &handler.CallbacksHandler{
+			Config: config,
+			Dialer: &callback.Dialer{
+				Calls:  twilioClient.Api,
+				Config: config,
+			},
+			Logger: logger,
+			Presence: &presence.Tracker{
+				Store: store,
+			},
+			Schedule: schedule,
+		},
+		Export: &handler.ExportHandler{
+			Config: config,
+			Exporter: &export.Exporter{
+				Config:     config,
+				History:    callHistory,
+				Voicemails: voicemails,
+			},
+			I18n:   i18n,
+			Logger: logger,
+		},
+		Health: &handler.HealthHandler{
+			Config: config,
+			Logger: logger,
+		},
+		Recordings: &handler.RecordingsHandler{
+			Logger: logger,
+		},
+		Reports: &handler.ReportsHandler{
+			Config:     config,
+			History:    callHistory,
+			I18n:       i18n,
+			Logger:     logger,
+			Mailer:     mailer,
+			Voicemails: voicemails,
+		},
+		SMS: &handler.SMSHandler{
+			Config:         config,
+			I18n:           i18n,
+			HandlerFactory: handlerFactory,
+			History:        callHistory,
+			Logger:         logger,
+			Mailer:         mailer,
+			Presence: &presence.Tracker{
+				Store: store,
+			},
+		},
+		Voice: &handler.VoiceHandler{
+			Audit:     trail,
+			Callbacks: schedule,
+			Callers: &callers.Directory{
+				Store: store,
+			},
+			Conferences: &conference.Bridge{
+				Calls:  twilioClient.Api,
+				Config: config,
 				Logger: logger,
+				Store:  store,
 			},
-			SMS: &handler.SMSHandler{
-				Config:         config,
-				I18n:           i18n,
-				HandlerFactory: handlerFactory,
-				Logger:         logger,
-				Mailer:         mailer,
+			Config:         config,
+			Emailer:        mailer,
+			HandlerFactory: handlerFactory,
+			History:        callHistory,
+			I18n:           i18n,
+			Logger:         logger,
+			Metrics:        metrics,
+			Presence: &presence.Tracker{
+				Store: store,
+			},
+			Screener: &screening.Screener{
+				Config: config,
+				Logger: logger,
+				Store:  store,
 			},
-			Voice: &handler.VoiceHandler{
-				Config:         config,
-				Emailer:        mailer,
-				HandlerFactory: handlerFactory,
-				Logger:         logger,
-				Twigen: &twigen.Voice{
-					Config: config,
-					I18n:   i18n,
-					Logger: logger,
-				},
+			Twigen: &twigen.Voice{
+				Audio:   audioLibrary,
+				Config:  config,
+				I18n:    i18n,
+				Logger:  logger,
+				Metrics: metrics,
 			},
+			Voicemails: voicemails,
 		},
 	}
 }
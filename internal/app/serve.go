@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// FlushTimeout bounds how long work left after draining requests, e.g. exporting spans, may take.
+// It has its own deadline so a drain using all of the shutdown timeout doesn't drop the last spans,
+// and it's short so both fit in the 10s Cloud Run waits after SIGTERM.
+const FlushTimeout = 2 * time.Second
+
+// Serve serves HTTP requests on listener until ctx is done, e.g. when the instance receives SIGTERM.
+// It then stops accepting connections, lets in-flight requests finish for up to shutdownTimeout,
+// and calls flush with a deadline of [FlushTimeout].
+func Serve(
+	ctx context.Context,
+	srv *http.Server,
+	listener net.Listener,
+	logger *slog.Logger,
+	shutdownTimeout time.Duration,
+	flush func(context.Context) error,
+) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("Listening and serving HTTP", "addr", listener.Addr().String())
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("failed to serve HTTP: %w", err)
+	case <-ctx.Done():
+	}
+
+	// stop accepting connections, then let in-flight webhooks finish, including the emails they send
+	logger.Info("Shutting down, draining in-flight requests", "timeout", shutdownTimeout.String())
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelDrain()
+	drainErr := srv.Shutdown(drainCtx)
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), FlushTimeout)
+	defer cancelFlush()
+
+	err := errors.Join(drainErr, flush(flushCtx))
+	if err != nil {
+		return fmt.Errorf("failed to shut down gracefully: %w", err)
+	}
+	logger.Info("Shut down gracefully")
+	return nil
+}
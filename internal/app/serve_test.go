@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// serveBlocking serves a handler which blocks until release is closed, and returns once a request is in flight.
+func serveBlocking(
+	t *testing.T,
+	shutdownTimeout time.Duration,
+	flush func(context.Context) error,
+) (stop func(), release chan struct{}, response <-chan *http.Response, served <-chan error) {
+	t.Helper()
+
+	started := make(chan struct{})
+	release = make(chan struct{})
+	srv := &http.Server{ //nolint:exhaustruct
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			<-release
+			_, _ = w.Write([]byte("done"))
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	t.Cleanup(stop)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- Serve(ctx, srv, listener, logger, shutdownTimeout, flush)
+	}()
+
+	res := make(chan *http.Response, 1)
+	go func() {
+		r, err := http.Get("http://" + listener.Addr().String()) //nolint:noctx
+		if err != nil {
+			t.Error(err)
+		}
+		res <- r
+	}()
+	<-started
+
+	return stop, release, res, serveErr
+}
+
+func TestServe_drainsInFlightRequests(t *testing.T) {
+	t.Parallel()
+
+	flushed := false
+	stop, release, response, served := serveBlocking(t, 5*time.Second, func(context.Context) error {
+		flushed = true
+		return nil
+	})
+
+	stop() // as on SIGTERM
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-served:
+		t.Fatalf("Serve() returned with a request in flight: %v", err)
+	default:
+	}
+	close(release)
+
+	res := <-response
+	if res == nil {
+		t.FailNow()
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil || res.StatusCode != http.StatusOK || string(body) != "done" {
+		t.Errorf("in-flight request = %d %q, %v, want 200 done", res.StatusCode, body, err)
+	}
+
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v, want nil", err)
+	}
+	if !flushed {
+		t.Error("flush wasn't called")
+	}
+}
+
+func TestServe_flushesAfterDrainTimeout(t *testing.T) {
+	t.Parallel()
+
+	var flushErr error
+	stop, release, _, served := serveBlocking(t, 10*time.Millisecond, func(ctx context.Context) error {
+		flushErr = ctx.Err()
+		return nil
+	})
+	defer close(release)
+
+	stop()
+	err := <-served
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Serve() = %v, want deadline exceeded draining requests", err)
+	}
+	if flushErr != nil {
+		t.Errorf("flush had no time left after the drain: %v", flushErr)
+	}
+}
@@ -1,25 +1,40 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/infotecho/ocomms/internal/config"
 	"github.com/infotecho/ocomms/internal/handler"
 	"github.com/infotecho/ocomms/internal/log"
+	"github.com/infotecho/ocomms/internal/metrics"
+	"github.com/infotecho/ocomms/internal/tracing"
 )
 
 // ServerFactory creates the O-Comms [http.Server] instance.
 type ServerFactory struct {
 	Config     config.Config
 	Logger     *slog.Logger
+	Metrics    *metrics.Metrics
+	MuxFactory *handler.MuxFactory
+	Tenants    []Tenant
+}
+
+// Tenant is another organization hosted by O-Comms, with its own handlers.
+type Tenant struct {
+	Config     config.Tenant
 	MuxFactory *handler.MuxFactory
 }
 
 // Server returns an [http.Server] instance for O-Comms.
 func (sf ServerFactory) Server() http.Server {
-	mux := sf.MuxFactory.Mux()
-	handler := appyMilddleware(mux)
+	var mux http.Handler = sf.MuxFactory.Mux()
+	if len(sf.Tenants) > 0 {
+		mux = newTenantRouter(mux, sf.Config, sf.Tenants)
+	}
+	handler := sf.appyMilddleware(sf.Metrics.Middleware(mux))
 
 	return http.Server{
 		Addr:              ":" + sf.Config.Server.Port,
@@ -31,6 +46,18 @@ func (sf ServerFactory) Server() http.Server {
 	}
 }
 
-func appyMilddleware(h http.Handler) http.Handler {
-	return log.Middleware(h)
+// appyMilddleware wraps h in middleware. Only the innermost middleware serving a copy of requests,
+// and those inside it, see the route pattern the mux sets on requests.
+func (sf ServerFactory) appyMilddleware(h http.Handler) http.Handler {
+	return log.Middleware(tracing.Middleware(log.AccessLog(sf.Logger, h)))
+}
+
+// Close finishes the work handlers left to do in the background, e.g. posting audit events,
+// once the server stopped serving requests.
+func (sf ServerFactory) Close(ctx context.Context) error {
+	errs := []error{sf.MuxFactory.Audit.Trail.Close(ctx)}
+	for _, tenant := range sf.Tenants {
+		errs = append(errs, tenant.MuxFactory.Audit.Trail.Close(ctx))
+	}
+	return errors.Join(errs...)
 }
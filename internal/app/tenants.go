@@ -0,0 +1,97 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/infotecho/ocomms/internal/config"
+)
+
+// tenantRouter routes Twilio webhooks to the handlers of the tenant they are for,
+// identified by the company DID that was called or texted, or else by Twilio account.
+// The calling DID only identifies the tenant when no known company DID was called,
+// like calls from a tenant's DID to agents.
+// Call back links carry the company DID and client's number in their query string, like webhook parameters.
+// Requests for no tenant are handled by the hosting organization's handlers.
+// Admin API requests aren't from Twilio, and are for the tenant whose ID is in the tenant query parameter.
+//
+// Tenants are selected before Twilio signatures are validated, with the tenant's own auth token,
+// so a request claiming to be for a tenant is only handled if signed by that tenant's account.
+type tenantRouter struct {
+	fallback  http.Handler
+	byID      map[string]http.Handler
+	byDID     map[string]http.Handler
+	byAccount map[string]http.Handler
+}
+
+func newTenantRouter(fallback http.Handler, conf config.Config, tenants []Tenant) tenantRouter {
+	router := tenantRouter{
+		fallback:  fallback,
+		byID:      map[string]http.Handler{},
+		byDID:     map[string]http.Handler{},
+		byAccount: map[string]http.Handler{},
+	}
+
+	// calls from a tenant's DID to one of the hosting organization's are the hosting organization's
+	for _, profile := range conf.Profiles {
+		for _, did := range profile.DIDs {
+			router.byDID[did] = fallback
+		}
+	}
+
+	for _, tenant := range tenants {
+		mux := tenant.MuxFactory.Mux()
+		router.byID[tenant.Config.ID] = mux
+		for _, did := range tenant.Config.DIDs {
+			router.byDID[did] = mux
+		}
+		// tenants sharing the hosting organization's account are only identified by DID
+		if tenant.Config.AccountSID != "" && tenant.Config.AccountSID != conf.Twilio.AccountSID {
+			router.byAccount[tenant.Config.AccountSID] = mux
+		}
+	}
+
+	return router
+}
+
+func (tr tenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/admin/") {
+		tr.serveAdmin(w, r)
+		return
+	}
+
+	// handlers report malformed forms
+	_ = r.ParseForm()
+
+	// calls made by agents or to agents in conference mode are from the company DID
+	if mux, ok := tr.byDID[r.Form.Get("To")]; ok {
+		mux.ServeHTTP(w, r)
+		return
+	}
+	if mux, ok := tr.byDID[r.Form.Get("From")]; ok {
+		mux.ServeHTTP(w, r)
+		return
+	}
+
+	if mux, ok := tr.byAccount[r.Form.Get("AccountSid")]; ok {
+		mux.ServeHTTP(w, r)
+		return
+	}
+
+	tr.fallback.ServeHTTP(w, r)
+}
+
+func (tr tenantRouter) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("tenant")
+	if id == "" {
+		tr.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	mux, ok := tr.byID[id]
+	if !ok {
+		http.Error(w, "unknown tenant", http.StatusNotFound)
+		return
+	}
+	mux.ServeHTTP(w, r)
+}
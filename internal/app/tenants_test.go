@@ -0,0 +1,229 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/infotecho/ocomms/internal/callback"
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/fakes"
+	"github.com/infotecho/ocomms/internal/metrics"
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+func TestTenantRouter(t *testing.T) {
+	t.Parallel()
+
+	respond := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(name))
+		})
+	}
+	router := tenantRouter{
+		fallback: respond("home"),
+		byID:     map[string]http.Handler{"partner": respond("partner")},
+		byDID: map[string]http.Handler{
+			"+16135550199": respond("partner"),
+			"+16137775652": respond("home"), // a DID of one of the hosting organization's profiles
+		},
+		byAccount: map[string]http.Handler{"AC2": respond("reseller")},
+	}
+
+	tests := []struct {
+		name string
+		path string `exhaustruct:"optional"`
+		form url.Values
+		want string
+	}{
+		{
+			name: "called DID",
+			form: url.Values{"AccountSid": {"AC1"}, "From": {"+17052223434"}, "To": {"+16135550199"}},
+			want: "partner",
+		},
+		{
+			name: "agent call from DID",
+			form: url.Values{"AccountSid": {"AC1"}, "From": {"+16135550199"}, "To": {"+17778889999"}},
+			want: "partner",
+		},
+		{
+			name: "call from DID to hosting organization",
+			form: url.Values{"AccountSid": {"AC1"}, "From": {"+16135550199"}, "To": {"+16137775652"}},
+			want: "home",
+		},
+		{
+			name: "account",
+			form: url.Values{"AccountSid": {"AC2"}, "From": {"+17052223434"}, "To": {"+16135550100"}},
+			want: "reseller",
+		},
+		{
+			name: "no tenant",
+			form: url.Values{"AccountSid": {"AC1"}, "From": {"+17052223434"}, "To": {"+16137775650"}},
+			want: "home",
+		},
+		{
+			name: "admin API for tenant",
+			path: "/admin/scheduled-callbacks?tenant=partner",
+			form: url.Values{"To": {"+16137775650"}},
+			want: "partner",
+		},
+		{
+			name: "admin API",
+			path: "/admin/scheduled-callbacks",
+			form: url.Values{"To": {"+16135550199"}},
+			want: "home",
+		},
+		{
+			name: "admin API for unknown tenant",
+			path: "/admin/scheduled-callbacks?tenant=reseller",
+			form: url.Values{},
+			want: "unknown tenant\n",
+		},
+	}
+
+	for _, test := range tests {
+		path := test.path
+		if path == "" {
+			path = "/voice/inbound"
+		}
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(test.form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if got := rec.Body.String(); got != test.want {
+			t.Errorf("%s: routed to %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestTenantScheduledCallback(t *testing.T) {
+	t.Parallel()
+
+	conf, err := config.Load(true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	conf.Admin.Token = "admin-token"
+	conf.Server.BaseURL = "https://ocomms.example.com"
+
+	agent := conf.Agents[0]
+	agent.ID = "parker"
+	agent.DIDs.Mobile = "+17778881111"
+	tenant := config.Tenant{
+		ID:         "partner",
+		AccountSID: "",
+		AuthToken:  conf.Twilio.AuthToken,
+		AdminToken: "",
+		PIN:        "",
+		DIDs:       []string{"+16135550199"},
+		Agents:     []config.Agent{agent},
+		Profiles:   nil,
+		Mail:       conf.Mail,
+		Messages:   nil,
+	}
+
+	logger := slog.Default()
+	storage := store.NewMemory()
+	stats := metrics.New()
+	host := wireMux(conf, logger, storage, stats)
+	hostCalls := &fakes.TwilioCalls{}
+	host.Callbacks.Dialer.Calls = hostCalls
+	partner := wireMux(conf.ForTenant(tenant), logger, store.Prefixed{Prefix: "tenants/partner/", Store: storage}, stats)
+	partnerCalls := &fakes.TwilioCalls{}
+	partner.Callbacks.Dialer.Calls = partnerCalls
+
+	err = partner.Voice.Callbacks.Add(context.Background(), callback.Request{
+		CallSid: "CA1",
+		Number:  "+17052223434",
+		To:      "+16135550199",
+		Lang:    "en",
+		Time:    time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := newTenantRouter(host.Mux(), conf, []Tenant{{Config: tenant, MuxFactory: partner}})
+	launch := func(path string) string {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	if got := launch("/admin/scheduled-callbacks"); got != `{"launched":0,"waiting":0}` {
+		t.Errorf("The hosting organization has no call backs to launch: %s", got)
+	}
+	if got := launch("/admin/scheduled-callbacks?tenant=partner"); got != `{"launched":1,"waiting":0}` {
+		t.Errorf("Unexpected launch result for tenant: %s", got)
+	}
+
+	wantCreated := []string{
+		"CA00000000000000000000000000000001 To=+17778881111 From=+16135550199" +
+			" Url=https://ocomms.example.com/voice/bridge-callback?number=%2B17052223434 StatusCallback=",
+	}
+	if diff := cmp.Diff(wantCreated, partnerCalls.CreatedCalls()); diff != "" {
+		t.Errorf("Created calls mismatch (-want +got):\n%s", diff)
+	}
+	if created := hostCalls.CreatedCalls(); len(created) > 0 {
+		t.Errorf("The hosting organization's account should make no calls: %v", created)
+	}
+}
+
+func TestTenantAdminToken(t *testing.T) {
+	t.Parallel()
+
+	conf, err := config.Load(true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	conf.Admin.Token = "admin-token"
+	tenant := config.Tenant{
+		ID:         "partner",
+		AccountSID: "",
+		AuthToken:  "",
+		AdminToken: "partner-token",
+		PIN:        "",
+		DIDs:       []string{"+16135550199"},
+		Agents:     nil,
+		Profiles:   nil,
+		Mail:       conf.Mail,
+		Messages:   nil,
+	}
+
+	logger := slog.Default()
+	storage := store.NewMemory()
+	stats := metrics.New()
+	host := wireMux(conf, logger, storage, stats)
+	partner := wireMux(conf.ForTenant(tenant), logger, store.Prefixed{Prefix: "tenants/partner/", Store: storage}, stats)
+	router := newTenantRouter(host.Mux(), conf, []Tenant{{Config: tenant, MuxFactory: partner}})
+
+	tests := []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{"/admin/scheduled-callbacks", "admin-token", http.StatusOK},
+		{"/admin/scheduled-callbacks", "partner-token", http.StatusUnauthorized},
+		{"/admin/scheduled-callbacks?tenant=partner", "partner-token", http.StatusOK},
+		{"/admin/scheduled-callbacks?tenant=partner", "admin-token", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, test.path, nil)
+		req.Header.Set("Authorization", "Bearer "+test.token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != test.want {
+			t.Errorf("POST %s with %s: got status %d, want %d", test.path, test.token, rec.Code, test.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package audio provides recorded audio prompts, played to callers in place of text-to-speech.
+package audio
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Path is the path under which bundled audio prompts are served.
+const Path = "/audio/"
+
+//go:embed all:assets
+var assets embed.FS
+
+// Assets returns the audio prompts bundled with O-Comms, in a directory for each language.
+func Assets() fs.FS {
+	sub, err := fs.Sub(assets, "assets")
+	if err != nil {
+		panic(err) // "assets" is a valid path
+	}
+	return sub
+}
+
+// Library resolves references to audio prompts, which are either URLs or files in FS under a directory per language.
+type Library struct {
+	FS fs.FS
+}
+
+// URL returns the URL from which Twilio can play an audio prompt in lang,
+// or false if the prompt is a file that wasn't recorded for lang.
+func (l Library) URL(lang string, asset string) (string, bool) {
+	if IsURL(asset) {
+		return asset, true
+	}
+
+	name := path.Join(lang, asset)
+	if _, err := fs.Stat(l.FS, name); err != nil {
+		return "", false
+	}
+	return Path + name, true
+}
+
+// Check verifies that an audio prompt in lang exists, requesting it from its host if it is a URL.
+func (l Library) Check(ctx context.Context, client *http.Client, lang string, asset string) error {
+	if !IsURL(asset) {
+		_, err := fs.Stat(l.FS, path.Join(lang, asset))
+		if err != nil {
+			return fmt.Errorf("failed to find audio prompt: %w", err)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, asset, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request for audio prompt: %w", err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request audio prompt: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to request audio prompt: %s", res.Status)
+	}
+	return nil
+}
+
+// Handler serves the library's files under [Path].
+func (l Library) Handler() http.Handler {
+	return http.StripPrefix(strings.TrimSuffix(Path, "/"), http.FileServerFS(l.FS))
+}
+
+// IsURL reports whether an asset is a remote URL rather than an embedded file.
+func IsURL(asset string) bool {
+	return strings.HasPrefix(asset, "https://") || strings.HasPrefix(asset, "http://")
+}
@@ -0,0 +1,131 @@
+// Package audit records the decisions O-Comms makes while routing calls, e.g. to answer
+// "why did this call go to voicemail?" from a call's timeline rather than by reading code.
+package audit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+// Kind is a kind of call-flow decision.
+type Kind string
+
+// Kinds of call-flow decisions, and the decisions they take.
+const (
+	KindScreening     Kind = "screening"      // allow, challenge or block, or challenge-passed or challenge-failed
+	KindLanguage      Kind = "language"       // the language chosen
+	KindBusinessHours Kind = "business-hours" // open or closed
+	KindRing          Kind = "ring"           // ring-group, or no-agents once all ring groups were tried
+	KindDialStatus    Kind = "dial-status"    // how a DialCallStatus was interpreted: answered, next-group, abandoned
+	KindVoicemail     Kind = "voicemail"      // rerecord or left
+)
+
+// Event is a decision made during a call.
+type Event struct {
+	CallSid  string            `json:"callSid"` // the caller's call
+	Time     time.Time         `json:"time"`
+	Kind     Kind              `json:"kind"`
+	Decision string            `json:"decision"`
+	Details  map[string]string `json:"details,omitempty"` // e.g. the reason for a decision
+}
+
+// Sink receives audit events.
+type Sink interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Timeliner is a [Sink] that keeps the events of calls.
+type Timeliner interface {
+	Sink
+
+	// Timeline returns the events of a call, in the order they were recorded.
+	Timeline(ctx context.Context, callSid string) ([]Event, error)
+}
+
+// ErrNoTimeline is returned when no sink keeps the events of calls.
+var ErrNoTimeline = errors.New("no audit sink keeps call timelines")
+
+// Trail records the decisions made during calls to sinks.
+// A nil *Trail records nothing, e.g. for tools that don't route calls.
+type Trail struct {
+	Logger *slog.Logger
+	Sinks  []Sink
+}
+
+// Record records a decision made during a call to all sinks.
+// Sinks failing are logged, as auditing must never fail a call.
+func (t *Trail) Record(ctx context.Context, callSid string, kind Kind, decision string, details map[string]string) {
+	if t == nil {
+		return
+	}
+
+	event := Event{
+		CallSid:  callSid,
+		Time:     time.Now(),
+		Kind:     kind,
+		Decision: decision,
+		Details:  details,
+	}
+	for _, sink := range t.Sinks {
+		err := sink.Record(ctx, event)
+		if err != nil {
+			t.Logger.ErrorContext(ctx, "Error recording audit event", "err", err, "kind", kind)
+		}
+	}
+}
+
+// Timeline returns the events of a call from the first sink keeping them.
+func (t *Trail) Timeline(ctx context.Context, callSid string) ([]Event, error) {
+	if t != nil {
+		for _, sink := range t.Sinks {
+			if timeliner, ok := sink.(Timeliner); ok {
+				return timeliner.Timeline(ctx, callSid) //nolint:wrapcheck
+			}
+		}
+	}
+	return nil, ErrNoTimeline
+}
+
+// Close closes the sinks which record events in the background, once they've recorded those pending,
+// e.g. when the server shuts down.
+func (t *Trail) Close(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, sink := range t.Sinks {
+		if closer, ok := sink.(interface {
+			Close(ctx context.Context) error
+		}); ok {
+			errs = append(errs, closer.Close(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewTrail creates a [Trail] recording to the sinks enabled in application config.
+func NewTrail(conf config.Config, logger *slog.Logger, store store.Store) *Trail {
+	trail := &Trail{Logger: logger, Sinks: nil}
+	if conf.Audit.Log {
+		trail.Sinks = append(trail.Sinks, LogSink{Logger: logger})
+	}
+	if conf.Audit.Storage {
+		trail.Sinks = append(trail.Sinks, &StoreSink{Store: store, Retention: conf.Audit.Retention}) //nolint:exhaustruct
+	}
+	if conf.Audit.Webhook.URL != "" {
+		trail.Sinks = append(trail.Sinks, NewWebhookSink(
+			&http.Client{Timeout: conf.Audit.Webhook.Timeout}, //nolint:exhaustruct
+			conf.Audit.Webhook.URL,
+			conf.Audit.Webhook.QueueSize,
+			logger,
+		))
+	}
+	return trail
+}
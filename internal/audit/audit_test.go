@@ -0,0 +1,162 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/audit"
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+func TestTrail(t *testing.T) {
+	t.Parallel()
+
+	posted := make(chan audit.Event, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		var event audit.Event
+		err := json.NewDecoder(r.Body).Decode(&event)
+		if err != nil {
+			t.Errorf("Failed to decode posted event: %v", err)
+		}
+		posted <- event
+	}))
+	defer webhook.Close()
+
+	var conf config.Config
+	conf.Audit.Storage = true
+	conf.Audit.Webhook.URL = webhook.URL
+	conf.Audit.Retention = 24 * time.Hour
+	conf.Audit.Webhook.Timeout = time.Second
+	conf.Audit.Webhook.QueueSize = 10
+	trail := audit.NewTrail(conf, slog.Default(), store.NewMemory())
+
+	ctx := context.Background()
+	trail.Record(ctx, "CA1", audit.KindBusinessHours, "closed", map[string]string{"profile": "sales"})
+	trail.Record(ctx, "CA2", audit.KindLanguage, "fr", nil)
+
+	if event := <-posted; event.CallSid != "CA1" || event.Decision != "closed" {
+		t.Errorf("Posted event = %+v, want CA1 closed", event)
+	}
+	<-posted
+
+	events, err := trail.Timeline(ctx, "CA1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Kind != audit.KindBusinessHours || events[0].Details["profile"] != "sales" {
+		t.Errorf("Timeline of CA1 = %+v, want its business hours decision", events)
+	}
+
+	if err := trail.Close(ctx); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+
+	var disabled *audit.Trail
+	disabled.Record(ctx, "CA1", audit.KindLanguage, "en", nil)
+	if _, err := disabled.Timeline(ctx, "CA1"); err == nil {
+		t.Error("Timeline() without storage should fail")
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	posted := make(chan audit.Event, 3)
+	webhook := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-release
+		var event audit.Event
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		posted <- event
+	}))
+	defer webhook.Close()
+
+	sink := audit.NewWebhookSink(webhook.Client(), webhook.URL, 1, slog.Default())
+	ctx := context.Background()
+
+	// calls don't wait for the webhook, and events are dropped once the queue is full
+	err := sink.Record(ctx, audit.Event{CallSid: "CA1"}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatal(err)
+	}
+	for sink.Record(ctx, audit.Event{CallSid: "CA2"}) != nil { //nolint:exhaustruct
+		// CA1 is being posted once it has left the queue
+		time.Sleep(time.Millisecond)
+	}
+	if err := sink.Record(ctx, audit.Event{CallSid: "CA3"}); !errors.Is(err, audit.ErrQueueFull) { //nolint:exhaustruct
+		t.Errorf("Record() with a full queue = %v, want %v", err, audit.ErrQueueFull)
+	}
+
+	// queued events are posted on shutdown, for as long as the shutdown allows
+	shortCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := sink.Close(shortCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() while posting = %v, want deadline exceeded", err)
+	}
+	close(release)
+	if err := sink.Close(ctx); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if first, second := <-posted, <-posted; first.CallSid != "CA1" || second.CallSid != "CA2" {
+		t.Errorf("Posted %s and %s, want CA1 and CA2", first.CallSid, second.CallSid)
+	}
+	if err := sink.Record(ctx, audit.Event{CallSid: "CA4"}); err == nil { //nolint:exhaustruct
+		t.Error("Record() after Close() should fail")
+	}
+}
+
+func TestStoreSink(t *testing.T) {
+	t.Parallel()
+
+	storage := store.NewMemory()
+	sink := &audit.StoreSink{Store: storage, Retention: 48 * time.Hour} //nolint:exhaustruct
+	ctx := context.Background()
+	now := time.Now()
+
+	// concurrent calls and webhooks of the same call don't lose events
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			callSid := []string{"CA1", "CA2"}[i%2]
+			err := sink.Record(ctx, audit.Event{CallSid: callSid, Time: now, Kind: audit.KindRing}) //nolint:exhaustruct
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, callSid := range []string{"CA1", "CA2"} {
+		if events, _ := sink.Timeline(ctx, callSid); len(events) != 10 {
+			t.Errorf("Timeline of %s has %d events, want 10", callSid, len(events))
+		}
+	}
+
+	// timelines are deleted after the retention period
+	for callSid, start := range map[string]time.Time{"CA-old": now.AddDate(0, 0, -3), "CA-recent": now.AddDate(0, 0, -1)} {
+		err := sink.Record(ctx, audit.Event{CallSid: callSid, Time: start, Kind: audit.KindLanguage}) //nolint:exhaustruct
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if events, _ := sink.Timeline(ctx, "CA-old"); len(events) != 0 {
+		t.Errorf("Timeline of an expired call = %+v, want none", events)
+	}
+	if _, ok, _ := storage.Get(ctx, "audit-days/"+now.AddDate(0, 0, -3).UTC().Format("2006-01-02")); ok {
+		t.Error("Index of expired calls wasn't deleted")
+	}
+	for _, callSid := range []string{"CA-recent", "CA1"} {
+		if events, _ := sink.Timeline(ctx, callSid); len(events) == 0 {
+			t.Errorf("Timeline of %s was deleted before the end of the retention period", callSid)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+// LogSink logs audit events, e.g. to be queried in Cloud Logging.
+type LogSink struct {
+	Logger *slog.Logger
+}
+
+// Record logs event.
+func (s LogSink) Record(ctx context.Context, event Event) error {
+	attrs := []any{"kind", event.Kind, "decision", event.Decision}
+	if len(event.Details) > 0 {
+		details := make([]any, 0, 2*len(event.Details)) //nolint:mnd
+		for key, value := range event.Details {
+			details = append(details, key, value)
+		}
+		attrs = append(attrs, slog.Group("details", details...))
+	}
+
+	s.Logger.InfoContext(ctx, "Call flow decision", attrs...)
+	return nil
+}
+
+// StoreSink keeps the events of each call in storage, to be viewed as a timeline.
+type StoreSink struct {
+	Store     store.Store
+	Retention time.Duration // timelines are deleted once their call started this long ago, or kept if 0
+
+	calls callLocks  // serializes updates to each call's timeline
+	days  sync.Mutex // serializes updates to the index of calls by day
+}
+
+func timelineKey(callSid string) string {
+	return "audit/" + callSid
+}
+
+func dayKey(day time.Time) string {
+	return "audit-days/" + day.UTC().Format("2006-01-02")
+}
+
+const prunedKey = "audit-pruned"
+
+// Record appends event to the timeline of its call.
+func (s *StoreSink) Record(ctx context.Context, event Event) error {
+	unlock := s.calls.lock(event.CallSid)
+	defer unlock()
+
+	events, err := s.load(ctx, event.CallSid)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(append(events, event))
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit events: %w", err)
+	}
+	err = s.Store.Set(ctx, timelineKey(event.CallSid), value)
+	if err != nil {
+		return fmt.Errorf("failed to set audit events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return s.index(ctx, event)
+	}
+	return nil
+}
+
+// index adds the call of its first event to the index of calls by day, and deletes expired timelines.
+func (s *StoreSink) index(ctx context.Context, event Event) error {
+	s.days.Lock()
+	defer s.days.Unlock()
+
+	var callSids []string
+	err := s.loadDay(ctx, event.Time, &callSids)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(append(callSids, event.CallSid))
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit index: %w", err)
+	}
+	err = s.Store.Set(ctx, dayKey(event.Time), value)
+	if err != nil {
+		return fmt.Errorf("failed to set audit index: %w", err)
+	}
+
+	if s.Retention == 0 {
+		return nil
+	}
+	err = store.PruneDays(ctx, s.Store, prunedKey, event.Time, time.Now().Add(-s.Retention), s.prune)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired audit events: %w", err)
+	}
+	return nil
+}
+
+// prune deletes the timelines of the calls started on day.
+func (s *StoreSink) prune(ctx context.Context, day time.Time) error {
+	var callSids []string
+	err := s.loadDay(ctx, day, &callSids)
+	if err != nil {
+		return err
+	}
+	for _, callSid := range callSids {
+		err = s.Store.Delete(ctx, timelineKey(callSid))
+		if err != nil {
+			return fmt.Errorf("failed to delete audit events: %w", err)
+		}
+	}
+	err = s.Store.Delete(ctx, dayKey(day))
+	if err != nil {
+		return fmt.Errorf("failed to delete audit index: %w", err)
+	}
+	return nil
+}
+
+// Timeline returns the events of a call, in the order they were recorded.
+func (s *StoreSink) Timeline(ctx context.Context, callSid string) ([]Event, error) {
+	unlock := s.calls.lock(callSid)
+	defer unlock()
+
+	return s.load(ctx, callSid)
+}
+
+func (s *StoreSink) load(ctx context.Context, callSid string) ([]Event, error) {
+	value, ok, err := s.Store.Get(ctx, timelineKey(callSid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	var events []Event
+	err = json.Unmarshal(value, &events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit events: %w", err)
+	}
+	return events, nil
+}
+
+func (s *StoreSink) loadDay(ctx context.Context, day time.Time, callSids *[]string) error {
+	value, ok, err := s.Store.Get(ctx, dayKey(day))
+	if err != nil {
+		return fmt.Errorf("failed to get audit index: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+
+	err = json.Unmarshal(value, callSids)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal audit index: %w", err)
+	}
+	return nil
+}
+
+// callLocks serializes updates to the timeline of each call, without calls waiting for each other.
+type callLocks struct {
+	mu    sync.Mutex
+	calls map[string]*callLock
+}
+
+type callLock struct {
+	mu      sync.Mutex
+	holders int // goroutines holding or waiting for mu, the lock is forgotten when none are left
+}
+
+// lock locks the timeline of a call, and returns the function unlocking it.
+func (l *callLocks) lock(callSid string) func() {
+	l.mu.Lock()
+	if l.calls == nil {
+		l.calls = map[string]*callLock{}
+	}
+	call, ok := l.calls[callSid]
+	if !ok {
+		call = &callLock{} //nolint:exhaustruct
+		l.calls[callSid] = call
+	}
+	call.holders++
+	l.mu.Unlock()
+
+	call.mu.Lock()
+	return func() {
+		call.mu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		call.holders--
+		if call.holders == 0 {
+			delete(l.calls, callSid)
+		}
+	}
+}
+
+// ErrQueueFull is returned when an event is recorded while the webhook is too slow to post queued events.
+var ErrQueueFull = errors.New("audit webhook queue is full")
+
+// WebhookSink posts audit events as JSON to a URL, e.g. of a data pipeline.
+// Events are queued and posted in the background, so calls don't wait for the webhook.
+type WebhookSink struct {
+	Client *http.Client
+	URL    string
+	Logger *slog.Logger
+
+	queue   chan Event
+	closing chan struct{}
+	closed  sync.Once
+	done    chan struct{}
+}
+
+// NewWebhookSink creates a [WebhookSink] queuing up to queueSize events, and starts posting them.
+func NewWebhookSink(client *http.Client, url string, queueSize int, logger *slog.Logger) *WebhookSink {
+	sink := &WebhookSink{
+		Client:  client,
+		URL:     url,
+		Logger:  logger,
+		queue:   make(chan Event, queueSize),
+		closing: make(chan struct{}),
+		closed:  sync.Once{},
+		done:    make(chan struct{}),
+	}
+	go sink.run()
+
+	return sink
+}
+
+// Record queues event to be posted. Events are dropped if the queue is full, e.g. while the webhook is down.
+func (s *WebhookSink) Record(_ context.Context, event Event) error {
+	select {
+	case <-s.closing:
+		return errors.New("audit webhook is closed")
+	default:
+	}
+
+	select {
+	case s.queue <- event:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops queuing events and waits for those queued to be posted, until ctx is done.
+func (s *WebhookSink) Close(ctx context.Context) error {
+	s.closed.Do(func() { close(s.closing) })
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to post queued audit events: %w", ctx.Err())
+	}
+}
+
+// run posts queued events until the sink is closed and its queue is empty.
+func (s *WebhookSink) run() {
+	defer close(s.done)
+
+	for {
+		select {
+		case event := <-s.queue:
+			s.post(event)
+		case <-s.closing:
+			for {
+				select {
+				case event := <-s.queue:
+					s.post(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// post posts event to the webhook, after the request recording it may have been served.
+func (s *WebhookSink) post(event Event) {
+	err := s.postJSON(context.Background(), event)
+	if err != nil {
+		s.Logger.Error("Error posting audit event", "err", err, "callSid", event.CallSid, "kind", event.Kind)
+	}
+}
+
+func (s *WebhookSink) postJSON(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create audit webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post audit event: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("audit webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
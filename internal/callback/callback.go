@@ -0,0 +1,82 @@
+// Package callback calls clients back on behalf of agents:
+// Twilio rings the agent first, then bridges them to the client with the company DID as caller ID.
+package callback
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/conference"
+	"github.com/infotecho/ocomms/internal/config"
+	openapi "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// Path is the path of call back links sent to agents.
+const Path = "/callbacks"
+
+// ErrInvalidLink is returned when verifying a call back link that was tampered with or expired.
+var ErrInvalidLink = errors.New("invalid or expired call back link")
+
+// Dialer calls clients back through the Twilio REST API.
+type Dialer struct {
+	Calls  conference.CallsAPI
+	Config config.Config
+}
+
+// Call rings agentDID from companyDID, and connects the agent to number once they answer the call at actionBridge.
+func (d Dialer) Call(agentDID string, companyDID string, number string, actionBridge string) error {
+	params := &openapi.CreateCallParams{}
+	params.SetTo(agentDID)
+	params.SetFrom(companyDID)
+	params.SetUrl(d.Config.Server.BaseURL + actionBridge + "?number=" + url.QueryEscape(number))
+	params.SetTimeout(d.Config.Twilio.Timeouts.DialAgents)
+
+	_, err := d.Calls.CreateCall(params)
+	if err != nil {
+		return fmt.Errorf("failed to call agent: %w", err)
+	}
+	return nil
+}
+
+// Link returns a link for agents to call back number, who called or texted companyDID.
+// The link is signed with the Twilio auth token, and expires after the configured expiry.
+func Link(conf config.Config, companyDID string, number string, now time.Time) string {
+	query := url.Values{}
+	query.Set("From", number)
+	query.Set("To", companyDID)
+	query.Set("expires", strconv.FormatInt(now.Add(conf.Callbacks.LinkExpiry).Unix(), 10))
+	query.Set("sig", signature(conf, query))
+
+	return conf.Server.BaseURL + Path + "?" + query.Encode()
+}
+
+// Verify checks that the query of a call back link was signed by [Link] and hasn't expired.
+func Verify(conf config.Config, query url.Values, now time.Time) error {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || now.Unix() > expires {
+		return ErrInvalidLink
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(query.Get("sig"))
+	if err != nil {
+		return ErrInvalidLink
+	}
+	want, _ := base64.RawURLEncoding.DecodeString(signature(conf, query))
+	if !hmac.Equal(sig, want) {
+		return ErrInvalidLink
+	}
+
+	return nil
+}
+
+func signature(conf config.Config, query url.Values) string {
+	mac := hmac.New(sha256.New, []byte(conf.Twilio.AuthToken))
+	mac.Write([]byte(query.Get("From") + "|" + query.Get("To") + "|" + query.Get("expires")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,140 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/profiles"
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+const key = "callbacks/scheduled"
+
+// maxSlotDays limits how far ahead slots are offered, e.g. over a long weekend.
+const maxSlotDays = 7
+
+// Request is a call back a caller scheduled.
+type Request struct {
+	CallSid string    `json:"callSid"` // call during which the caller scheduled the call back
+	Number  string    `json:"number"`  // number to call back
+	To      string    `json:"to"`      // company DID the caller called
+	Lang    string    `json:"lang"`
+	Time    time.Time `json:"time"` // start of the slot the caller chose
+}
+
+// Schedule persists the call backs callers scheduled until they are launched.
+type Schedule struct {
+	Store store.Store
+
+	mu sync.Mutex // serializes updates from concurrent calls
+}
+
+// Add schedules a call back.
+func (s *Schedule) Add(ctx context.Context, request Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	return s.save(ctx, append(requests, request))
+}
+
+// Pending returns the scheduled call backs, earliest first.
+func (s *Schedule) Pending(ctx context.Context) ([]Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(requests, func(a, b Request) int { return a.Time.Compare(b.Time) })
+	return requests, nil
+}
+
+// Remove unschedules the call back requested during a call.
+func (s *Schedule) Remove(ctx context.Context, callSid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	return s.save(ctx, slices.DeleteFunc(requests, func(r Request) bool { return r.CallSid == callSid }))
+}
+
+func (s *Schedule) load(ctx context.Context) ([]Request, error) {
+	value, ok, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled call backs: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	var requests []Request
+	err = json.Unmarshal(value, &requests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal scheduled call backs: %w", err)
+	}
+	return requests, nil
+}
+
+func (s *Schedule) save(ctx context.Context, requests []Request) error {
+	value, err := json.Marshal(requests)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scheduled call backs: %w", err)
+	}
+
+	err = s.Store.Set(ctx, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to set scheduled call backs: %w", err)
+	}
+	return nil
+}
+
+// Slots returns the next slots to offer a caller of a profile, in now's location.
+// Slots start at multiples of the configured slot length, at least one slot length from now,
+// within the profile's business hours. A slot is full once capacity call backs are booked in it.
+func Slots(conf config.Config, profile config.Profile, now time.Time, capacity int, booked []time.Time) []time.Time {
+	length := conf.Callbacks.Scheduled.SlotLength
+	if length <= 0 || capacity <= 0 {
+		return nil
+	}
+
+	earliest := now.Add(length)
+	slot := earliest.Truncate(length)
+	if slot.Before(earliest) {
+		slot = slot.Add(length)
+	}
+
+	var slots []time.Time
+	for last := now.AddDate(0, 0, maxSlotDays); slot.Before(last); slot = slot.Add(length) {
+		if len(slots) == conf.Callbacks.Scheduled.Slots {
+			break
+		}
+
+		open, err := profiles.Open(profile, slot)
+		if err != nil || !open {
+			continue
+		}
+		taken := 0
+		for _, t := range booked {
+			if t.Equal(slot) {
+				taken++
+			}
+		}
+		if taken < capacity {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
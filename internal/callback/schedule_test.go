@@ -0,0 +1,64 @@
+package callback_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/infotecho/ocomms/internal/callback"
+	"github.com/infotecho/ocomms/internal/config"
+)
+
+func TestSlots(t *testing.T) {
+	t.Parallel()
+
+	var conf config.Config
+	conf.Callbacks.Scheduled.SlotLength = 30 * time.Minute
+	conf.Callbacks.Scheduled.Slots = 3
+
+	var profile config.Profile
+	profile.Hours = []config.Hours{
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Open: "09:00", Close: "17:00"},
+	}
+
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, 11, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		booked []time.Time
+		want   []time.Time
+	}{
+		{
+			name:   "at least a slot from now",
+			now:    at(4, 9, 40),
+			booked: nil,
+			want:   []time.Time{at(4, 10, 30), at(4, 11, 0), at(4, 11, 30)},
+		},
+		{
+			name:   "full slots skipped",
+			now:    at(4, 9, 30),
+			booked: []time.Time{at(4, 10, 0), at(4, 10, 0), at(4, 10, 30)},
+			want:   []time.Time{at(4, 10, 30), at(4, 11, 0), at(4, 11, 30)},
+		},
+		{
+			name:   "next business day",
+			now:    at(8, 16, 15),
+			booked: nil,
+			want:   []time.Time{at(11, 9, 0), at(11, 9, 30), at(11, 10, 0)},
+		},
+	}
+
+	for _, test := range tests {
+		got := callback.Slots(conf, profile, test.now, 2, test.booked)
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("%s: Slots() mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+
+	if got := callback.Slots(conf, profile, at(4, 9, 0), 0, nil); len(got) > 0 {
+		t.Errorf("Slots() without agents = %v, want none", got)
+	}
+}
@@ -0,0 +1,125 @@
+// Package callers remembers information about repeat callers.
+package callers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+// anonymousNumbers are the values Twilio uses in place of a caller's number when caller ID is withheld.
+//
+//nolint:gochecknoglobals
+var anonymousNumbers = []string{
+	"anonymous",
+	"+266696687",   // ANONYMOUS
+	"+86282452253", // UNKNOWN
+	"+7378742833",  // RESTRICTED
+	"+2562533",     // BLOCKED
+}
+
+// Anonymous reports whether number stands for a caller who withheld their caller ID,
+// so it doesn't identify who called.
+func Anonymous(number string) bool {
+	return slices.Contains(anonymousNumbers, strings.ToLower(number))
+}
+
+// Directory stores per-caller preferences, keyed by the caller's phone number.
+type Directory struct {
+	Store store.Store
+}
+
+// PINFailures counts the incorrect PINs entered from a number, to lock it out after too many.
+type PINFailures struct {
+	Count       int       `json:"count"`
+	LockedUntil time.Time `json:"lockedUntil"` // PINs from the number are refused until then
+}
+
+func langKey(number string) string {
+	return "callers/" + number + "/lang"
+}
+
+func pinFailuresKey(number string) string {
+	return "callers/" + number + "/pinFailures"
+}
+
+// Language returns the language last selected by the caller, and whether one was found.
+func (d Directory) Language(ctx context.Context, number string) (string, bool, error) {
+	lang, ok, err := d.Store.Get(ctx, langKey(number))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get language for caller: %w", err)
+	}
+
+	return string(lang), ok, nil
+}
+
+// SetLanguage remembers the language selected by the caller.
+func (d Directory) SetLanguage(ctx context.Context, number string, lang string) error {
+	err := d.Store.Set(ctx, langKey(number), []byte(lang))
+	if err != nil {
+		return fmt.Errorf("failed to set language for caller: %w", err)
+	}
+
+	return nil
+}
+
+// PINFailures returns the incorrect PINs entered from number since its last correct PIN or lockout.
+func (d Directory) PINFailures(ctx context.Context, number string) (PINFailures, error) {
+	var failures PINFailures
+	value, ok, err := d.Store.Get(ctx, pinFailuresKey(number))
+	if err != nil {
+		return failures, fmt.Errorf("failed to get PIN failures for caller: %w", err)
+	}
+	if !ok {
+		return failures, nil
+	}
+
+	err = json.Unmarshal(value, &failures)
+	if err != nil {
+		return failures, fmt.Errorf("failed to parse PIN failures for caller: %w", err)
+	}
+	return failures, nil
+}
+
+// AddPINFailure counts an incorrect PIN entered from number, and locks the number out for lockout once
+// attempts PINs in a row were incorrect. It returns the updated failures.
+func (d Directory) AddPINFailure(
+	ctx context.Context,
+	number string,
+	attempts int,
+	lockout time.Duration,
+) (PINFailures, error) {
+	failures, err := d.PINFailures(ctx, number)
+	if err != nil {
+		return failures, err
+	}
+
+	failures.Count++
+	if failures.Count >= attempts {
+		failures = PINFailures{Count: 0, LockedUntil: time.Now().Add(lockout)}
+	}
+
+	value, err := json.Marshal(failures)
+	if err != nil {
+		return failures, fmt.Errorf("failed to encode PIN failures for caller: %w", err)
+	}
+	err = d.Store.Set(ctx, pinFailuresKey(number), value)
+	if err != nil {
+		return failures, fmt.Errorf("failed to set PIN failures for caller: %w", err)
+	}
+	return failures, nil
+}
+
+// ResetPINFailures forgets the incorrect PINs entered from number, once a correct PIN was entered.
+func (d Directory) ResetPINFailures(ctx context.Context, number string) error {
+	err := d.Store.Delete(ctx, pinFailuresKey(number))
+	if err != nil {
+		return fmt.Errorf("failed to reset PIN failures for caller: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+// Package conference connects callers to agents through named Twilio conferences,
+// which lets agents transfer calls or bring in a colleague.
+package conference
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/store"
+	openapi "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// ErrNoConference is returned when ringing agents into a conference that isn't open.
+var ErrNoConference = errors.New("no open conference")
+
+// CallsAPI is an interface for the calls resource of [github.com/twilio/twilio-go/rest/api/v2010.ApiService].
+type CallsAPI interface {
+	CreateCall(params *openapi.CreateCallParams) (*openapi.ApiV2010Call, error)
+	UpdateCall(sid string, params *openapi.UpdateCallParams) (*openapi.ApiV2010Call, error)
+}
+
+// State tracks the calls involved in a conference between a caller and agents.
+type State struct {
+	CallerSid    string   `json:"callerSid"`
+	Fallback     string   `json:"fallback"`     // action the caller is redirected to if no agent joins
+	Ringing      []string `json:"ringing"`      // SIDs of calls to agents who haven't joined yet
+	Joined       []string `json:"joined"`       // SIDs of calls to agents in the conference
+	Answered     bool     `json:"answered"`     // whether an agent ever joined
+	Transferring bool     `json:"transferring"` // whether the last agent left the caller for another
+}
+
+// Bridge rings agents through the Twilio REST API into the conference where a caller waits.
+type Bridge struct {
+	Calls  CallsAPI
+	Config config.Config
+	Logger *slog.Logger
+	Store  store.Store
+
+	// mu serializes updates from concurrent status callbacks.
+	// It isn't held during REST API requests, which would hold up the callbacks of every conference.
+	mu sync.Mutex
+}
+
+const roomPrefix = "call-"
+
+// Room returns the name of the conference in which a caller waits for an agent.
+func Room(callerSid string) string {
+	return roomPrefix + callerSid
+}
+
+// CallerSid returns the SID of the call of the caller waiting in room.
+func CallerSid(room string) string {
+	return strings.TrimPrefix(room, roomPrefix)
+}
+
+func key(room string) string {
+	return "conferences/" + room
+}
+
+// Open starts tracking a conference for a caller, who is redirected to actionFallback if no agent joins.
+func (b *Bridge) Open(ctx context.Context, room string, callerSid string, actionFallback string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.save(ctx, room, State{CallerSid: callerSid, Fallback: actionFallback})
+}
+
+// Active returns whether the caller is still waiting or talking in a conference.
+func (b *Bridge) Active(ctx context.Context, room string) (bool, error) {
+	_, ok, err := b.state(ctx, room)
+	return ok, err
+}
+
+// Ring calls agentDIDs from callerID, to join room once they accept the call at actionAcceptCall.
+// Twilio reports the end of each agent's call to actionAgentStatus.
+// If transfer is set, the caller is redirected to the fallback action rather than hung up
+// should none of the agents join after the agents in the conference leave.
+func (b *Bridge) Ring(
+	ctx context.Context,
+	room string,
+	callerID string,
+	agentDIDs []string,
+	actionAcceptCall string,
+	actionAgentStatus string,
+	transfer bool,
+) error {
+	_, ok, err := b.state(ctx, room)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNoConference
+	}
+
+	var ringing []string
+	var errs []error
+	for _, agentDID := range agentDIDs {
+		params := &openapi.CreateCallParams{}
+		params.SetTo(agentDID)
+		params.SetFrom(callerID)
+		params.SetUrl(b.url(actionAcceptCall))
+		params.SetStatusCallback(b.url(actionAgentStatus))
+		params.SetTimeout(b.Config.Twilio.Timeouts.DialAgents)
+
+		call, err := b.Calls.CreateCall(params)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to call agent: %w", err))
+			continue
+		}
+		ringing = append(ringing, *call.Sid)
+	}
+	if len(errs) == len(agentDIDs) {
+		return errors.Join(errs...)
+	}
+	for _, err := range errs {
+		b.Logger.ErrorContext(ctx, "Error ringing agent into conference", "err", err)
+	}
+
+	b.mu.Lock()
+	state, ok, err := b.load(ctx, room)
+	if err == nil && ok {
+		state.Ringing = append(state.Ringing, ringing...)
+		state.Transferring = state.Transferring || transfer
+		err = b.save(ctx, room, state)
+	}
+	b.mu.Unlock()
+
+	if err != nil || !ok {
+		// the caller left while agents were called
+		for _, sid := range ringing {
+			b.hangup(ctx, sid)
+		}
+	}
+	if !ok && err == nil {
+		return ErrNoConference
+	}
+	return err
+}
+
+// Join records that an agent accepted a call to join room, and hangs up on the other agents being rung.
+// It returns false if the agent's call is no longer wanted, e.g. because another agent joined first,
+// and whether the agent is the first to answer the caller, rather than an agent the call was transferred to.
+func (b *Bridge) Join(ctx context.Context, room string, callSid string) (bool, bool, error) {
+	b.mu.Lock()
+	state, ok, err := b.load(ctx, room)
+	if err != nil || !ok || !slices.Contains(state.Ringing, callSid) {
+		b.mu.Unlock()
+		return false, false, err
+	}
+
+	first := !state.Answered
+	others := slices.DeleteFunc(state.Ringing, func(sid string) bool { return sid == callSid })
+	state.Ringing = nil
+	state.Joined = append(state.Joined, callSid)
+	state.Answered = true
+	state.Transferring = false
+	err = b.save(ctx, room, state)
+	b.mu.Unlock()
+
+	for _, sid := range others {
+		b.hangup(ctx, sid)
+	}
+	return true, first, err
+}
+
+// Leave records the end of an agent's call. Once no agents are left in or ringing into the conference,
+// the caller is hung up if the call was answered, or redirected to the fallback action otherwise.
+func (b *Bridge) Leave(ctx context.Context, room string, callSid string) error {
+	b.mu.Lock()
+	state, ok, err := b.load(ctx, room)
+	if err != nil || !ok {
+		b.mu.Unlock()
+		return err
+	}
+
+	isCall := func(sid string) bool { return sid == callSid }
+	state.Ringing = slices.DeleteFunc(state.Ringing, isCall)
+	state.Joined = slices.DeleteFunc(state.Joined, isCall)
+	if len(state.Ringing) > 0 || len(state.Joined) > 0 {
+		err = b.save(ctx, room, state)
+		b.mu.Unlock()
+		return err
+	}
+	err = b.delete(ctx, room)
+	b.mu.Unlock()
+
+	if state.Answered && !state.Transferring {
+		b.hangup(ctx, state.CallerSid)
+	} else {
+		params := &openapi.UpdateCallParams{}
+		params.SetUrl(b.url(state.Fallback))
+		_, err := b.Calls.UpdateCall(state.CallerSid, params)
+		if err != nil {
+			b.Logger.ErrorContext(ctx, "Error redirecting caller out of conference", "err", err)
+		}
+	}
+
+	return nil
+}
+
+// Close stops tracking a conference after the caller left, hanging up on any agents still being rung.
+func (b *Bridge) Close(ctx context.Context, room string) error {
+	b.mu.Lock()
+	state, ok, err := b.load(ctx, room)
+	if err == nil && ok {
+		err = b.delete(ctx, room)
+	}
+	b.mu.Unlock()
+	if !ok {
+		return err
+	}
+
+	for _, sid := range state.Ringing {
+		b.hangup(ctx, sid)
+	}
+	return err
+}
+
+// state returns the state of a conference, if it's open.
+func (b *Bridge) state(ctx context.Context, room string) (State, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.load(ctx, room)
+}
+
+func (b *Bridge) hangup(ctx context.Context, callSid string) {
+	params := &openapi.UpdateCallParams{}
+	params.SetStatus("completed")
+	_, err := b.Calls.UpdateCall(callSid, params)
+	if err != nil {
+		b.Logger.ErrorContext(ctx, "Error hanging up call", "err", err, "callSid", callSid)
+	}
+}
+
+func (b *Bridge) url(action string) string {
+	return b.Config.Server.BaseURL + action
+}
+
+func (b *Bridge) load(ctx context.Context, room string) (State, bool, error) {
+	value, ok, err := b.Store.Get(ctx, key(room))
+	if err != nil {
+		return State{}, false, fmt.Errorf("failed to get conference: %w", err)
+	}
+	if !ok {
+		return State{}, false, nil
+	}
+
+	var state State
+	err = json.Unmarshal(value, &state)
+	if err != nil {
+		return State{}, false, fmt.Errorf("failed to unmarshal conference: %w", err)
+	}
+
+	return state, true, nil
+}
+
+func (b *Bridge) save(ctx context.Context, room string, state State) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conference: %w", err)
+	}
+
+	err = b.Store.Set(ctx, key(room), value)
+	if err != nil {
+		return fmt.Errorf("failed to set conference: %w", err)
+	}
+	return nil
+}
+
+func (b *Bridge) delete(ctx context.Context, room string) error {
+	err := b.Store.Delete(ctx, key(room))
+	if err != nil {
+		return fmt.Errorf("failed to delete conference: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 
 import (
 	"log/slog"
+	"slices"
 	"time"
 )
 
@@ -10,55 +11,269 @@ import (
 
 // Config is the unmarshalled representation of config.yaml.
 type Config struct {
+	Agents   []Agent   `json:"agents"`
+	DIDs     []string  `json:"dids"` // company DIDs, which calls made through the admin API may come from
+	Profiles []Profile `json:"profiles"`
+	Tenants  []Tenant  `json:"tenants"`
+
 	Server struct {
+		BaseURL  string `json:"baseURL"` // public URL of O-Comms, for links in emails and calls made by Twilio REST API
 		Port     string `json:"port"`
 		Timeouts struct {
 			ReadHeaderTimeout time.Duration `jsonschema:"type=string"`
 			ReadTimeout       time.Duration `jsonschema:"type=string"`
 			WriteTimeout      time.Duration `jsonschema:"type=string"`
 			IdleTimeout       time.Duration `jsonschema:"type=string"`
+			ShutdownTimeout   time.Duration `jsonschema:"type=string"` // to drain in-flight requests on SIGTERM
 		} `json:"timeouts"`
+		Readiness struct { // GET /readyz
+			CheckDependencies bool          `json:"checkDependencies"` // also check that Twilio and SendGrid APIs are reachable
+			Timeout           time.Duration `json:"timeout"           jsonschema:"type=string"`
+		} `json:"readiness"`
 	} `json:"server"`
 
+	Admin struct {
+		Token string `json:"token"` // bearer token for the admin API
+	} `json:"admin"`
+
+	Callbacks struct {
+		LinkExpiry time.Duration `json:"linkExpiry" jsonschema:"type=string"` // validity of call back links in emails
+		Scheduled  struct {      // call backs requested by callers
+			Enabled    bool          `json:"enabled"` // offer callers to schedule a call back instead of leaving a voicemail
+			SlotLength time.Duration `json:"slotLength" jsonschema:"type=string"`
+			Slots      int           `json:"slots"`                               // number of slots offered, at most 9
+			RetryFor   time.Duration `json:"retryFor"   jsonschema:"type=string"` // how long to wait for an available agent after the slot
+		} `json:"scheduled"`
+	} `json:"callbacks"`
+
 	Logging struct {
 		Format LogFormat  `json:"format" jsonschema:"type=string,enum=text,enum=json"`
 		Level  slog.Level `json:"level"  jsonschema:"type=string,enum=debug,enum=info,enum=warn,enum=error"`
+		Redact struct {   // personal information masked in logs, unless run with -logging.unredacted
+			PhoneNumbers  bool `json:"phoneNumbers"`  // all but the last 4 digits of phone numbers
+			MessageBodies bool `json:"messageBodies"` // text message bodies
+			Emails        bool `json:"emails"`        // all but the first character of email addresses
+		} `json:"redact"`
 	} `json:"logging"`
 
+	Tracing struct {
+		Endpoint    string  `json:"endpoint"`    // OTLP/HTTP endpoint to export spans to, or empty to only propagate trace context
+		SampleRatio float64 `json:"sampleRatio"` // share of requests traced, unless the caller's trace was sampled
+	} `json:"tracing"`
+
+	Audit struct { // sinks of call-flow decisions, see GET /admin/calls/{callSid}/timeline
+		Log       bool          `json:"log"`
+		Storage   bool          `json:"storage"`                            // keeps call timelines
+		Retention time.Duration `json:"retention" jsonschema:"type=string"` // how long call timelines are kept, forever if 0
+		Webhook   struct {
+			URL       string        `json:"url"` // receives each decision as a JSON POST, or empty to disable
+			Timeout   time.Duration `json:"timeout"   jsonschema:"type=string"`
+			QueueSize int           `json:"queueSize"` // decisions waiting to be posted, more are dropped
+		} `json:"webhook"`
+	} `json:"audit"`
+
+	History struct { // calls and text messages, for reports and exports
+		Retention time.Duration `json:"retention" jsonschema:"type=string"` // how long they're kept, forever if 0
+	} `json:"history"`
+
+	Reports struct {
+		Weekly struct { // activity summary emailed by POST /admin/reports/weekly, e.g. every Monday from Cloud Scheduler
+			Lang   string   `json:"lang"`
+			MailTo []string `json:"mailTo"` // recipients, or the default mail recipient if empty
+		} `json:"weekly"`
+	} `json:"reports"`
+
 	I18N struct {
-		DefaultLang string `json:"defaultLang"`
+		DefaultLang string         `json:"defaultLang"`
+		TimeZone    string         `json:"timeZone"`
+		Overrides   map[string]any `json:"overrides"` // messages replacing those of i18n files, by language
 	} `json:"i18n"`
 
-	Mail struct {
-		From struct {
-			Name    string `json:"name"`
-			Address string `json:"address"`
-		} `json:"from"`
-		To struct {
-			Name    string `json:"name"`
-			Address string `json:"address"`
-		} `json:"to"`
-		SendGrid struct {
-			APIKey string `json:"apiKey"`
-		} `json:"sendgrid"`
-	} `json:"mail"`
+	Mail Mail `json:"mail"`
+
+	Routing struct {
+		Menu []MenuOption `json:"menu"`
+	} `json:"routing"`
+
+	Screening struct {
+		Allowlist  []string `json:"allowlist"`
+		Blocklist  []string `json:"blocklist"`
+		Anonymous  string   `json:"anonymous"  jsonschema:"enum=allow,enum=challenge,enum=block"`
+		StirShaken struct {
+			Enabled        bool   `json:"enabled"`
+			MinAttestation string `json:"minAttestation" jsonschema:"enum=A,enum=B,enum=C"`
+			Failed         string `json:"failed"         jsonschema:"enum=allow,enum=challenge,enum=block"`
+			Unverified     string `json:"unverified"     jsonschema:"enum=allow,enum=challenge,enum=block"`
+		} `json:"stirShaken"`
+	} `json:"screening"`
+
+	Storage struct {
+		Driver string `json:"driver" jsonschema:"enum=memory,enum=file"`
+		Dir    string `json:"dir"` // directory of the file driver, e.g. a volume mounted in Cloud Run
+	} `json:"storage"`
 
 	Twilio struct {
-		AgentDIDs           []string          `json:"agentDIDs"`
-		AuthToken           string            `json:"authToken"`
-		Languages           map[string]string `json:"languages"`
-		RecordInboundCalls  bool              `json:"recordInboundCalls"`
-		RecordOutboundCalls bool              `json:"recordOutboundCalls"`
-		Timeouts            struct {          // time in seconds
+		AccountSID          string              `json:"accountSID"`
+		AuthToken           string              `json:"authToken"`
+		Languages           map[string]Language `json:"languages"`
+		RecordInboundCalls  bool                `json:"recordInboundCalls"`
+		RecordOutboundCalls bool                `json:"recordOutboundCalls"`
+		Timeouts            struct {            // time in seconds
 			DialAgents           int `json:"dialAgents"`
 			GatherLanguage       int `json:"gatherLanguage"`
+			GatherLanguageChange int `json:"gatherLanguageChange"`
+			GatherLanguageSpeech int `json:"gatherLanguageSpeech"` // after each but the last option of a spoken language menu
 			GatherOutboundNumber int `json:"gatherOutboundNumber"`
 			GatherAcceptCall     int `json:"gatherAcceptCall"`
 			GatherStartVoicemail int `json:"gatherStartVoicemail"`
+			GatherScreening      int `json:"gatherScreening"`
+			GatherAgentPIN       int `json:"gatherAgentPIN"`
+			GatherAgentMenu      int `json:"gatherAgentMenu"`
+			GatherMenu           int `json:"gatherMenu"`
+			GatherTransfer       int `json:"gatherTransfer"`
+			GatherCallback       int `json:"gatherCallback"`
 		} `json:"timeouts"`
+		Speech struct {
+			MinConfidence float64 `json:"minConfidence"`
+			Menus         struct {
+				Language  bool `json:"language"`
+				Voicemail bool `json:"voicemail"`
+			} `json:"menus"`
+		} `json:"speech"`
+		Outbound struct {
+			AllowedCountryCodes []string      `json:"allowedCountryCodes"`
+			DeniedPrefixes      []string      `json:"deniedPrefixes"`
+			PIN                 string        `json:"pin"`
+			PINAttempts         int           `json:"pinAttempts"` // incorrect PINs before a number is locked out
+			PINLockout          time.Duration `json:"pinLockout" jsonschema:"type=string"`
+		} `json:"outbound"`
+		Conference struct {
+			Enabled bool `json:"enabled"`
+		} `json:"conference"`
 	} `json:"twilio"`
 }
 
+// Language configures how Twilio speaks and recognizes speech in one of the i18n languages.
+type Language struct {
+	Code  string `json:"code"`  // Twilio language code, e.g. en-US
+	Voice string `json:"voice"` // Twilio text-to-speech voice, e.g. Polly.Joanna-Neural, or empty for the default
+}
+
+// Agent is a member of staff who takes calls from clients.
+type Agent struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	DIDs  struct {
+		Mobile string `json:"mobile"`
+		Desk   string `json:"desk"`
+	} `json:"dids"`
+	Languages     []string `json:"languages"`
+	Skills        []string `json:"skills"`
+	Notifications struct { // email notifications sent to the agent
+		TextMessage       bool `json:"textMessage"`
+		Voicemail         bool `json:"voicemail"`
+		ScheduledCallback bool `json:"scheduledCallback"` // when a caller schedules a call back
+	} `json:"notifications"`
+}
+
+// PhoneNumbers returns the agent's configured phone numbers.
+func (a Agent) PhoneNumbers() []string {
+	var numbers []string
+	for _, number := range []string{a.DIDs.Mobile, a.DIDs.Desk} {
+		if number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+
+	return numbers
+}
+
+// Mail configures notification emails.
+type Mail struct {
+	From struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+	} `json:"from"`
+	To struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+	} `json:"to"`
+	SendGrid struct {
+		APIKey string `json:"apiKey"`
+	} `json:"sendgrid"`
+}
+
+// Tenant is another organization hosted on this deployment, with its own Twilio account or DIDs.
+type Tenant struct {
+	ID         string         `json:"id"` // namespaces the tenant's stored state
+	AccountSID string         `json:"accountSID"`
+	AuthToken  string         `json:"authToken"`
+	AdminToken string         `json:"adminToken"` // bearer token for the tenant's admin API, the host's if empty
+	PIN        string         `json:"pin"`        // agents' outbound PIN, the host's if empty
+	DIDs       []string       `json:"dids"`       // company DIDs, identifying the tenant if it shares a Twilio account
+	Agents     []Agent        `json:"agents"`
+	Profiles   []Profile      `json:"profiles"`
+	Mail       Mail           `json:"mail"`
+	Messages   map[string]any `json:"messages"` // i18n message overrides by language
+}
+
+// ForTenant returns the configuration of a tenant, which shares everything but its own settings with c.
+// Tenants inherit the rest from the hosting organization, e.g. screening, call backs, routing, reports,
+// the outbound dialing policy and timeouts, as well as its admin token and PIN unless they set their own.
+func (c Config) ForTenant(tenant Tenant) Config {
+	c.Agents = tenant.Agents
+	c.DIDs = tenant.DIDs
+	c.Profiles = tenant.Profiles
+	c.Tenants = nil
+	c.I18N.Overrides = tenant.Messages
+	c.Mail = tenant.Mail
+	c.Twilio.AccountSID = tenant.AccountSID
+	c.Twilio.AuthToken = tenant.AuthToken
+	if tenant.AdminToken != "" {
+		c.Admin.Token = tenant.AdminToken
+	}
+	if tenant.PIN != "" {
+		c.Twilio.Outbound.PIN = tenant.PIN
+	}
+
+	return c
+}
+
+// CompanyDIDs returns the company DIDs of the configuration, including those of its profiles.
+func (c Config) CompanyDIDs() []string {
+	dids := slices.Clone(c.DIDs)
+	for _, profile := range c.Profiles {
+		dids = append(dids, profile.DIDs...)
+	}
+	return dids
+}
+
+// Profile configures how calls and text messages to some of the company's DIDs are handled,
+// e.g. to run a support line and a sales line on the same deployment.
+type Profile struct {
+	ID        string       `json:"id"`        // keys the profile's greeting in i18n messages
+	DIDs      []string     `json:"dids"`      // company DIDs this profile applies to
+	Languages []string     `json:"languages"` // offered to callers in order, the language menu is skipped if only one
+	Menu      []MenuOption `json:"menu"`
+	Agents    []string     `json:"agents"` // IDs of agents who take calls, all agents if empty
+	MailTo    []string     `json:"mailTo"` // notification recipients, instead of agents who opted in if not empty
+	Hours     []Hours      `json:"hours"`  // business hours, open at all times if empty
+}
+
+// Hours is a daily opening period, in the time zone of config.I18N.
+type Hours struct {
+	Days  []string `json:"days"  jsonschema:"enum=mon,enum=tue,enum=wed,enum=thu,enum=fri,enum=sat,enum=sun"`
+	Open  string   `json:"open"  jsonschema:"pattern=^([01][0-9]|2[0-4]):[0-5][0-9]$"` // e.g. 09:00
+	Close string   `json:"close" jsonschema:"pattern=^([01][0-9]|2[0-4]):[0-5][0-9]$"` // e.g. 17:00, or 24:00 for midnight
+}
+
+// MenuOption maps a key pressed by callers in the IVR menu to the skill group of agents they need.
+type MenuOption struct {
+	Digit string `json:"digit"`
+	Skill string `json:"skill"`
+}
+
 // LogFormat determines the output format of logs: JSON or plain text.
 type LogFormat = string
 
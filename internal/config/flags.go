@@ -5,10 +5,18 @@ import (
 )
 
 //nolint:gochecknoglobals
-var loggingFormat = flag.String("logging.format", "", "Logging format (json or text)")
+var (
+	loggingFormat     = flag.String("logging.format", "", "Logging format (json or text)")
+	loggingUnredacted = flag.Bool("logging.unredacted", false, "Log personal information, for local development")
+)
 
 func applyCommandLineFlags(config *Config) {
 	if *loggingFormat != "" {
 		config.Logging.Format = *loggingFormat
 	}
+	if *loggingUnredacted {
+		config.Logging.Redact.PhoneNumbers = false
+		config.Logging.Redact.MessageBodies = false
+		config.Logging.Redact.Emails = false
+	}
 }
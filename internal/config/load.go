@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"reflect"
+	"slices"
 
 	"github.com/go-viper/mapstructure/v2"
 	_ "github.com/joho/godotenv/autoload" // load .env
@@ -56,9 +57,47 @@ func Load(allowUndefinedEnvVariables bool) (Config, error) {
 
 	applyCommandLineFlags(&config)
 
+	err = validate(config)
+	if err != nil {
+		return Config{}, err
+	}
+
 	return config, nil
 }
 
+// validate rejects settings that decode fine but would silently misbehave, e.g. an empty screening action.
+func validate(config Config) error {
+	var errs []error
+
+	actions := []string{"allow", "challenge", "block"}
+	screening := []struct {
+		key    string
+		action string
+	}{
+		{"screening.anonymous", config.Screening.Anonymous},
+		{"screening.stirShaken.failed", config.Screening.StirShaken.Failed},
+		{"screening.stirShaken.unverified", config.Screening.StirShaken.Unverified},
+	}
+	for _, setting := range screening {
+		if !slices.Contains(actions, setting.action) {
+			errs = append(errs, fmt.Errorf("invalid %s action %q, expected one of %v", setting.key, setting.action, actions))
+		}
+	}
+
+	if config.Twilio.Conference.Enabled && config.Server.BaseURL == "" {
+		errs = append(errs, errors.New("server.baseURL is required when twilio.conference.enabled"))
+	}
+	if config.Callbacks.Scheduled.Enabled && config.Storage.Driver == "memory" {
+		errs = append(errs, errors.New("callbacks.scheduled.enabled requires a persistent storage driver, "+
+			"as call backs scheduled on an instance would be lost"))
+	}
+	if config.Audit.Webhook.URL != "" && config.Audit.Webhook.QueueSize <= 0 {
+		errs = append(errs, errors.New("audit.webhook.queueSize must be positive when audit.webhook.url is set"))
+	}
+
+	return errors.Join(errs...)
+}
+
 //nolint:ireturn
 func stringToLogLevelHookFunc() mapstructure.DecodeHookFunc {
 	return func(
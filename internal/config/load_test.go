@@ -0,0 +1,62 @@
+package config_test
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// TestLoad_deployedEnv loads the config as the deployed server does, with only the environment variables
+// k8s/service.yaml sets, e.g. to catch a variable referenced by config.yaml but not deployed.
+func TestLoad_deployedEnv(t *testing.T) {
+	configFile, err := os.ReadFile("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, match := range regexp.MustCompile(`\$\{(\w+)\}`).FindAllSubmatch(configFile, -1) {
+		t.Setenv(string(match[1]), "") // undefined, unless deployed
+	}
+
+	serviceFile, err := os.ReadFile("../../k8s/service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var service struct {
+		Spec struct {
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						Env []struct {
+							Name  string `yaml:"name"`
+							Value string `yaml:"value"`
+						} `yaml:"env"`
+					} `yaml:"containers"`
+				} `yaml:"spec"`
+			} `yaml:"template"`
+		} `yaml:"spec"`
+	}
+	err = yaml.Unmarshal(serviceFile, &service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range service.Spec.Template.Spec.Containers[0].Env {
+		value := env.Value
+		switch {
+		case value != "":
+		case strings.HasSuffix(env.Name, "_DID"):
+			value = "+16135550100"
+		default:
+			value = "secret" // from a secretKeyRef
+		}
+		t.Setenv(env.Name, value)
+	}
+
+	_, err = config.Load(false)
+	if err != nil {
+		t.Errorf("Load() with the deployed environment: %v", err)
+	}
+}
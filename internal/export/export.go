@@ -0,0 +1,322 @@
+// Package export writes the call and message history as CSV or NDJSON, e.g. for billing and audits.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/history"
+	"github.com/infotecho/ocomms/internal/phone"
+	"github.com/infotecho/ocomms/internal/voicemail"
+)
+
+// ErrInvalidOptions is returned for unknown kinds of records, formats or columns, before anything is written.
+var ErrInvalidOptions = errors.New("invalid export options")
+
+// Kind is a kind of exported records.
+type Kind string
+
+// Kinds of exported records.
+const (
+	KindCalls      Kind = "calls"
+	KindVoicemails Kind = "voicemails" // calls that went to voicemail
+	KindMessages   Kind = "messages"   // inbound text messages
+)
+
+// Format is a format of exported records.
+type Format string
+
+// Formats of exported records.
+const (
+	FormatCSV    Format = "csv"    // with a header row
+	FormatNDJSON Format = "ndjson" // one JSON object per line
+)
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	if f == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Options selects the records to export and how to write them.
+type Options struct {
+	Kind    Kind
+	Format  Format
+	From    time.Time
+	To      time.Time // exclusive
+	Columns []string  // all columns of the kind of records if empty
+	Redact  bool      // mask all but the last 4 digits of phone numbers
+}
+
+// Exporter streams records from the call history.
+type Exporter struct {
+	Config     config.Config
+	History    *history.Log
+	Voicemails *voicemail.Box
+}
+
+// column is a field of an exported record.
+type column[T any] struct {
+	name   string
+	number bool // a phone number, masked when redacting
+	value  func(record T) string
+}
+
+// voicemailRecord is a call that went to voicemail, with the state of its voicemail.
+type voicemailRecord struct {
+	call   history.Call
+	status string
+}
+
+// Columns returns the names of the columns of a kind of records, in their default order.
+func Columns(kind Kind) []string {
+	switch kind {
+	case KindCalls:
+		return columnNames(callColumns())
+	case KindVoicemails:
+		return columnNames(voicemailColumns(""))
+	case KindMessages:
+		return columnNames(messageColumns())
+	default:
+		return nil
+	}
+}
+
+// Export writes the records selected by opts to w, reading the history a day at a time.
+// It returns an error wrapping [ErrInvalidOptions] before writing anything if opts are invalid.
+func (e Exporter) Export(ctx context.Context, w io.Writer, opts Options) error {
+	switch opts.Kind {
+	case KindCalls:
+		return export(ctx, w, opts, callColumns(), e.History.Calls)
+	case KindVoicemails:
+		return export(ctx, w, opts, voicemailColumns(e.Config.Server.BaseURL), e.voicemails)
+	case KindMessages:
+		return export(ctx, w, opts, messageColumns(), e.History.Messages)
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidOptions, opts.Kind)
+	}
+}
+
+// voicemails returns the calls that went to voicemail from from until to,
+// with whether an agent handled their voicemail or deleted it since.
+func (e Exporter) voicemails(ctx context.Context, from time.Time, to time.Time) ([]voicemailRecord, error) {
+	calls, err := e.History.Calls(ctx, from, to)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped by history
+	}
+
+	var records []voicemailRecord
+	for _, call := range calls {
+		if call.Outcome != history.OutcomeVoicemail {
+			continue
+		}
+		vm, ok, err := e.Voicemails.Get(ctx, call.RecordingSid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get voicemail: %w", err)
+		}
+		status := "deleted"
+		if ok {
+			status = "unhandled"
+			if vm.Handled {
+				status = "handled"
+			}
+		}
+		records = append(records, voicemailRecord{call: call, status: status})
+	}
+	return records, nil
+}
+
+func export[T any](
+	ctx context.Context,
+	w io.Writer,
+	opts Options,
+	all []column[T],
+	fetch func(ctx context.Context, from time.Time, to time.Time) ([]T, error),
+) error {
+	columns, err := selectColumns(all, opts.Columns)
+	if err != nil {
+		return err
+	}
+	rows, err := newRowWriter(w, opts.Format, columnNames(columns))
+	if err != nil {
+		return err
+	}
+
+	values := make([]string, len(columns))
+	for day := opts.From; day.Before(opts.To); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		if end.After(opts.To) {
+			end = opts.To
+		}
+		records, err := fetch(ctx, day, end)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			for i, column := range columns {
+				values[i] = column.value(record)
+				if column.number && opts.Redact {
+					values[i] = phone.Mask(values[i])
+				}
+			}
+			err = rows.write(values)
+			if err != nil {
+				return err
+			}
+		}
+		err = rows.flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.flush() // the CSV header of empty ranges
+}
+
+// selectColumns returns the columns named, in order, or all columns if none are.
+func selectColumns[T any](all []column[T], names []string) ([]column[T], error) {
+	if len(names) == 0 {
+		return all, nil
+	}
+
+	columns := make([]column[T], len(names))
+	for i, name := range names {
+		j := slices.IndexFunc(all, func(c column[T]) bool { return c.name == name })
+		if j < 0 {
+			return nil, fmt.Errorf("%w: unknown column %q, expected one of %v", ErrInvalidOptions, name, columnNames(all))
+		}
+		columns[i] = all[j]
+	}
+	return columns, nil
+}
+
+func columnNames[T any](columns []column[T]) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	return names
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func callColumns() []column[history.Call] {
+	return []column[history.Call]{
+		{name: "callSid", number: false, value: func(c history.Call) string { return c.CallSid }},
+		{name: "start", number: false, value: func(c history.Call) string { return formatTime(c.Start) }},
+		{name: "from", number: true, value: func(c history.Call) string { return c.From }},
+		{name: "to", number: true, value: func(c history.Call) string { return c.To }},
+		{name: "lang", number: false, value: func(c history.Call) string { return c.Lang }},
+		{name: "outcome", number: false, value: func(c history.Call) string { return string(c.Outcome) }},
+		{name: "agent", number: false, value: func(c history.Call) string { return c.Agent }},
+		{name: "waitSeconds", number: false, value: func(c history.Call) string {
+			return strconv.Itoa(int(c.Wait.Round(time.Second).Seconds()))
+		}},
+	}
+}
+
+func voicemailColumns(baseURL string) []column[voicemailRecord] {
+	return []column[voicemailRecord]{
+		{name: "callSid", number: false, value: func(v voicemailRecord) string { return v.call.CallSid }},
+		{name: "time", number: false, value: func(v voicemailRecord) string { return formatTime(v.call.Start) }},
+		{name: "from", number: true, value: func(v voicemailRecord) string { return v.call.From }},
+		{name: "to", number: true, value: func(v voicemailRecord) string { return v.call.To }},
+		{name: "lang", number: false, value: func(v voicemailRecord) string { return v.call.Lang }},
+		{name: "recordingSid", number: false, value: func(v voicemailRecord) string { return v.call.RecordingSid }},
+		{name: "recordingUrl", number: false, value: func(v voicemailRecord) string {
+			return baseURL + "/recordings/" + v.call.RecordingSid
+		}},
+		{name: "status", number: false, value: func(v voicemailRecord) string { return v.status }},
+	}
+}
+
+func messageColumns() []column[history.Message] {
+	return []column[history.Message]{
+		{name: "messageSid", number: false, value: func(m history.Message) string { return m.MessageSid }},
+		{name: "time", number: false, value: func(m history.Message) string { return formatTime(m.Time) }},
+		{name: "from", number: true, value: func(m history.Message) string { return m.From }},
+		{name: "to", number: true, value: func(m history.Message) string { return m.To }},
+		{name: "body", number: false, value: func(m history.Message) string { return m.Body }},
+	}
+}
+
+// rowWriter writes exported records in a format.
+type rowWriter interface {
+	write(values []string) error
+	flush() error
+}
+
+func newRowWriter(w io.Writer, format Format, columns []string) (rowWriter, error) {
+	switch format {
+	case FormatCSV:
+		rows := csvWriter{csv.NewWriter(w)}
+		return rows, rows.write(columns)
+	case FormatNDJSON:
+		return ndjsonWriter{encoder: json.NewEncoder(w), columns: columns}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidOptions, format)
+	}
+}
+
+type csvWriter struct {
+	*csv.Writer
+}
+
+func (w csvWriter) write(values []string) error {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeFormula(value)
+	}
+	return w.Write(escaped) //nolint:wrapcheck
+}
+
+// phoneNumber matches E.164 phone numbers, masked or not, which spreadsheets read as numbers rather than formulas.
+//
+//nolint:gochecknoglobals
+var phoneNumber = regexp.MustCompile(`^\+[0-9*]+$`)
+
+// escapeFormula prefixes values spreadsheets would run as formulas with a quote, e.g. a text message
+// "=HYPERLINK(...)", so they're shown as text. Phone numbers are left as they are, so they read back unchanged.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) && !phoneNumber.MatchString(value) {
+		return "'" + value
+	}
+	return value
+}
+
+func (w csvWriter) flush() error {
+	w.Flush()
+	return w.Error() //nolint:wrapcheck
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+	columns []string
+}
+
+func (w ndjsonWriter) write(values []string) error {
+	record := make(map[string]string, len(values))
+	for i, value := range values {
+		record[w.columns[i]] = value
+	}
+	return w.encoder.Encode(record) //nolint:wrapcheck
+}
+
+func (w ndjsonWriter) flush() error {
+	return nil // each record is written as it's encoded
+}
@@ -0,0 +1,88 @@
+//go:build test
+
+package fakes
+
+import (
+	"fmt"
+	"sync"
+
+	openapi "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// TwilioCalls is a fake of the calls and recordings resources of [github.com/twilio/twilio-go/rest/api/v2010.ApiService].
+type TwilioCalls struct {
+	mu         sync.Mutex
+	created    []string
+	updated    []string
+	recordings []string // deleted recordings
+}
+
+// CreateCall fakes [github.com/twilio/twilio-go/rest/api/v2010.ApiService.CreateCall].
+func (tc *TwilioCalls) CreateCall(params *openapi.CreateCallParams) (*openapi.ApiV2010Call, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	sid := fmt.Sprintf("CA%032d", len(tc.created)+1)
+	tc.created = append(tc.created, fmt.Sprintf("%s To=%s From=%s Url=%s StatusCallback=%s",
+		sid, deref(params.To), deref(params.From), deref(params.Url), deref(params.StatusCallback),
+	))
+
+	return &openapi.ApiV2010Call{Sid: &sid}, nil
+}
+
+// UpdateCall fakes [github.com/twilio/twilio-go/rest/api/v2010.ApiService.UpdateCall].
+func (tc *TwilioCalls) UpdateCall(sid string, params *openapi.UpdateCallParams) (*openapi.ApiV2010Call, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	update := sid
+	if params.Status != nil {
+		update += " Status=" + *params.Status
+	}
+	if params.Url != nil {
+		update += " Url=" + *params.Url
+	}
+	tc.updated = append(tc.updated, update)
+
+	return &openapi.ApiV2010Call{Sid: &sid}, nil
+}
+
+// DeleteRecording fakes [github.com/twilio/twilio-go/rest/api/v2010.ApiService.DeleteRecording].
+func (tc *TwilioCalls) DeleteRecording(sid string, _ *openapi.DeleteRecordingParams) error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tc.recordings = append(tc.recordings, sid)
+	return nil
+}
+
+// DeletedRecordings returns the SID of each recording requested to be deleted from the fake.
+func (tc *TwilioCalls) DeletedRecordings() []string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	return append([]string(nil), tc.recordings...)
+}
+
+// CreatedCalls returns a summary of each call requested to be created by the fake, starting with its SID.
+func (tc *TwilioCalls) CreatedCalls() []string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	return append([]string(nil), tc.created...)
+}
+
+// UpdatedCalls returns a summary of each call update requested from the fake, starting with the call's SID.
+func (tc *TwilioCalls) UpdatedCalls() []string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	return append([]string(nil), tc.updated...)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
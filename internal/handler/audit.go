@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/infotecho/ocomms/internal/audit"
+	"github.com/infotecho/ocomms/internal/config"
+)
+
+// AuditHandler serves the decisions made during calls through the admin API.
+type AuditHandler struct {
+	Config config.Config
+	Logger *slog.Logger
+	Trail  *audit.Trail
+}
+
+// timeline is the response to a call timeline request.
+type timeline struct {
+	CallSid string        `json:"callSid"`
+	Events  []audit.Event `json:"events"`
+}
+
+// timeline serves the decisions made during a call, in order, e.g. to find out why it went to voicemail.
+func (h AuditHandler) timeline(w http.ResponseWriter, r *http.Request) {
+	if !adminAuthorized(h.Config, r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	callSid := r.PathValue("callSid")
+	events, err := h.Trail.Timeline(ctx, callSid)
+	if errors.Is(err, audit.ErrNoTimeline) {
+		http.Error(w, "audit storage is disabled", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error getting call timeline", "err", err)
+		http.Error(w, "failed to get call timeline", http.StatusInternalServerError)
+		return
+	}
+	if len(events) == 0 {
+		http.Error(w, "unknown call", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(timeline{CallSid: callSid, Events: events})
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error writing response", "err", err)
+	}
+}
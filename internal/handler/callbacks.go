@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/agents"
+	"github.com/infotecho/ocomms/internal/callback"
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/presence"
+	"github.com/infotecho/ocomms/internal/profiles"
+)
+
+// callbackPage lets an agent who followed a call back link choose who Twilio should ring.
+// Calls are only placed when the form is submitted, since email clients may follow links to scan them.
+//
+//nolint:gochecknoglobals
+var callbackPage = template.Must(template.New("callback").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Call back {{.Number}}</title>
+</head>
+<body>
+{{if .Calling}}
+<p>Calling you at {{.Calling}}. Answer to be connected to {{.Number}}.</p>
+{{else}}
+<h1>Call back {{.Number}}</h1>
+<p>We'll ring you first, then connect you to the client from the company number.</p>
+<form method="post">
+{{range .Agents}}<button name="agent" value="{{.ID}}">Ring {{.Name}}</button>
+{{end}}
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// CallbacksHandler handles requests to call clients back, from links in emails or the admin API,
+// and launches the call backs callers scheduled.
+type CallbacksHandler struct {
+	Config   config.Config
+	Dialer   *callback.Dialer
+	Logger   *slog.Logger
+	Presence *presence.Tracker
+	Schedule *callback.Schedule
+}
+
+// callbackRequest is the body of an admin API request to call a client back.
+type callbackRequest struct {
+	Agent  string `json:"agent"`  // ID of the agent to ring
+	Number string `json:"number"` // client's phone number
+	DID    string `json:"did"`    // company DID to call from
+}
+
+// confirm shows the page of a call back link.
+func (h CallbacksHandler) confirm(w http.ResponseWriter, r *http.Request) {
+	if err := callback.Verify(h.Config, r.URL.Query(), time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	profile := profiles.Select(h.Config, r.URL.Query().Get("To"))
+	candidates := slices.DeleteFunc(profiles.Agents(profile, h.Config.Agents), func(agent config.Agent) bool {
+		return len(agent.PhoneNumbers()) == 0
+	})
+
+	h.render(w, r, map[string]any{
+		"Number": r.URL.Query().Get("From"),
+		"Agents": candidates,
+	})
+}
+
+// call rings the agent selected on the page of a call back link.
+func (h CallbacksHandler) call(actionBridge string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if err := callback.Verify(h.Config, query, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		agent, ok := agents.ByID(h.Config.Agents, r.PostFormValue("agent"))
+		if !ok || len(agent.PhoneNumbers()) == 0 {
+			http.Error(w, "unknown agent", http.StatusBadRequest)
+			return
+		}
+
+		number, err := outboundNumber(h.Config, query.Get("From"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		agentDID := agent.PhoneNumbers()[0]
+		err = h.Dialer.Call(agentDID, query.Get("To"), number, actionBridge)
+		if err != nil {
+			h.Logger.ErrorContext(r.Context(), "Error calling back client", "err", err)
+			http.Error(w, "failed to call back", http.StatusBadGateway)
+			return
+		}
+
+		h.Logger.InfoContext(r.Context(), "Calling back client", "agent", agent.ID)
+		h.render(w, r, map[string]any{
+			"Number":  number,
+			"Calling": agentDID,
+		})
+	}
+}
+
+// apiCall rings an agent to call back a client, as requested through the admin API.
+func (h CallbacksHandler) apiCall(actionBridge string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req callbackRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		agent, ok := agents.ByID(h.Config.Agents, req.Agent)
+		if !ok || len(agent.PhoneNumbers()) == 0 {
+			http.Error(w, "unknown agent", http.StatusBadRequest)
+			return
+		}
+
+		if !slices.Contains(h.Config.CompanyDIDs(), req.DID) {
+			http.Error(w, "unknown DID", http.StatusBadRequest)
+			return
+		}
+
+		number, err := outboundNumber(h.Config, req.Number)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = h.Dialer.Call(agent.PhoneNumbers()[0], req.DID, number, actionBridge)
+		if err != nil {
+			h.Logger.ErrorContext(r.Context(), "Error calling back client", "err", err)
+			http.Error(w, "failed to call back", http.StatusBadGateway)
+			return
+		}
+
+		h.Logger.InfoContext(r.Context(), "Calling back client", "agent", agent.ID)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// launchScheduled rings an available agent for each scheduled call back that is due,
+// as requested through the admin API by a scheduler job.
+// Call backs stay scheduled while no agent is available, until the configured retry period is over.
+func (h CallbacksHandler) launchScheduled(actionBridge string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		ctx := r.Context()
+		pending, err := h.Schedule.Pending(ctx)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error getting scheduled call backs", "err", err)
+			http.Error(w, "failed to get scheduled call backs", http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+		busy := map[string]bool{} // agents already rung for an earlier call back
+		result := struct {
+			Launched int `json:"launched"`
+			Waiting  int `json:"waiting"`
+		}{}
+
+		for _, request := range pending {
+			if request.Time.After(now) {
+				break
+			}
+
+			agent, ok := h.availableAgent(ctx, request, busy)
+			if !ok {
+				if now.Sub(request.Time) < h.Config.Callbacks.Scheduled.RetryFor {
+					result.Waiting++
+					continue
+				}
+				h.Logger.ErrorContext(ctx, "No agent available for scheduled call back, giving up",
+					"callSid", request.CallSid, "time", request.Time)
+			} else {
+				err = h.Dialer.Call(agent.PhoneNumbers()[0], request.To, request.Number, actionBridge)
+				if err != nil {
+					h.Logger.ErrorContext(ctx, "Error launching scheduled call back", "err", err, "callSid", request.CallSid)
+					result.Waiting++
+					continue
+				}
+				h.Logger.InfoContext(ctx, "Launched scheduled call back", "agent", agent.ID, "callSid", request.CallSid)
+				busy[agent.ID] = true
+				result.Launched++
+			}
+
+			err = h.Schedule.Remove(ctx, request.CallSid)
+			if err != nil {
+				h.Logger.ErrorContext(ctx, "Error unscheduling call back", "err", err, "callSid", request.CallSid)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error writing response", "err", err)
+		}
+	}
+}
+
+// availableAgent returns the best matching agent to call back for a scheduled request, if any is available.
+func (h CallbacksHandler) availableAgent(
+	ctx context.Context,
+	request callback.Request,
+	busy map[string]bool,
+) (config.Agent, bool) {
+	profile := profiles.Select(h.Config, request.To)
+	available, err := h.Presence.Available(ctx, profiles.Agents(profile, h.Config.Agents))
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error getting agent presence", "err", err)
+	}
+
+	for _, group := range agents.RingGroups(available, request.Lang, "") {
+		for _, agent := range group {
+			if !busy[agent.ID] && len(agent.PhoneNumbers()) > 0 {
+				return agent, true
+			}
+		}
+	}
+	return config.Agent{}, false
+}
+
+// authorized checks the bearer token of an admin API request.
+func (h CallbacksHandler) authorized(r *http.Request) bool {
+	return adminAuthorized(h.Config, r)
+}
+
+// adminOnly serves requests to next only if they bear the admin token, e.g. to keep metrics private.
+func adminOnly(conf config.Config, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorized(conf, r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminAuthorized reports whether a request to the admin API bears the configured admin token.
+func adminAuthorized(conf config.Config, r *http.Request) bool {
+	token := conf.Admin.Token
+	got := r.Header.Get("Authorization")
+	return token != "" && subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+token)) == 1
+}
+
+func (h CallbacksHandler) render(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := callbackPage.Execute(w, data)
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "Error rendering call back page", "err", err)
+	}
+}
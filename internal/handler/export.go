@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/export"
+	"github.com/infotecho/ocomms/internal/i18n"
+)
+
+const exportDateLayout = "2006-01-02"
+
+// ExportHandler streams the call and message history through the admin API, e.g. for billing and audits.
+type ExportHandler struct {
+	Config   config.Config
+	Exporter *export.Exporter
+	I18n     *i18n.MessageProvider
+	Logger   *slog.Logger
+}
+
+// export streams the records of a kind from a range of days in the configured time zone, e.g.
+// GET /admin/export/calls?from=2024-11-01&to=2024-11-30&format=ndjson&columns=start,from,outcome&redact=false.
+// The format defaults to CSV, phone numbers are redacted unless redact=false, and to is inclusive.
+func (h ExportHandler) export(w http.ResponseWriter, r *http.Request) {
+	if !adminAuthorized(h.Config, r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	query := r.URL.Query()
+	opts, err := h.options(r.PathValue("kind"), query.Get("format"), query.Get("from"), query.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if columns := query.Get("columns"); columns != "" {
+		opts.Columns = strings.Split(columns, ",")
+	}
+	// phone numbers are only exported in full if asked to
+	opts.Redact = true
+	if redact := query.Get("redact"); redact != "" {
+		opts.Redact, err = strconv.ParseBool(redact)
+		if err != nil {
+			http.Error(w, "invalid redact, expected true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("%s-%s-%s.%s", opts.Kind, query.Get("from"), query.Get("to"), opts.Format)
+	w.Header().Set("Content-Type", opts.Format.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	err = h.Exporter.Export(ctx, w, opts)
+	if errors.Is(err, export.ErrInvalidOptions) {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		// the status was sent with the first records, the truncated export can only be logged
+		h.Logger.ErrorContext(ctx, "Error exporting history", "err", err, "kind", opts.Kind)
+	}
+}
+
+// options parses the kind, format and range of days of an export request.
+func (h ExportHandler) options(kind string, format string, from string, to string) (export.Options, error) {
+	opts := export.Options{Kind: export.Kind(kind), Format: export.Format(format)} //nolint:exhaustruct
+	if opts.Format == "" {
+		opts.Format = export.FormatCSV
+	}
+	if len(export.Columns(opts.Kind)) == 0 {
+		return opts, fmt.Errorf("unknown kind %q, expected calls, voicemails or messages", kind)
+	}
+	if opts.Format != export.FormatCSV && opts.Format != export.FormatNDJSON {
+		return opts, fmt.Errorf("unknown format %q, expected csv or ndjson", format)
+	}
+
+	loc := h.I18n.Local(time.Now()).Location()
+	start, err := time.ParseInLocation(exportDateLayout, from, loc)
+	if err != nil {
+		return opts, fmt.Errorf("invalid from date, expected YYYY-MM-DD: %w", err)
+	}
+	end, err := time.ParseInLocation(exportDateLayout, to, loc)
+	if err != nil {
+		return opts, fmt.Errorf("invalid to date, expected YYYY-MM-DD: %w", err)
+	}
+	opts.From = start
+	opts.To = end.AddDate(0, 0, 1) // the end of the last day
+
+	return opts, nil
+}
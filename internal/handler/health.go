@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/infotecho/ocomms/internal/config"
+)
+
+// dependencies are the APIs checked for reachability by readiness probes when configured.
+var dependencies = map[string]string{ //nolint:gochecknoglobals
+	"twilioAPI":   "https://api.twilio.com",
+	"sendgridAPI": "https://api.sendgrid.com",
+}
+
+// HealthHandler serves liveness and readiness probes, and the build info of the running server.
+// Its routes aren't Twilio webhooks, so they aren't signed.
+type HealthHandler struct {
+	Config config.Config
+	Logger *slog.Logger
+}
+
+// readiness is the response of readiness probes, with the result of each check.
+type readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"` // "ok", or why the check failed
+}
+
+// version is the build info of the running server.
+type version struct {
+	Module    string `json:"module"`
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified"`
+}
+
+// healthz reports that the server is live, i.e. able to serve requests.
+func (h HealthHandler) healthz(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// readyz reports whether the server is configured to handle webhooks:
+// Twilio and SendGrid credentials are set for the hosting organization and each tenant.
+func (h HealthHandler) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	checks := map[string]error{}
+	checkCredentials(checks, "", h.Config)
+	for _, tenant := range h.Config.Tenants {
+		checkCredentials(checks, ":"+tenant.ID, h.Config.ForTenant(tenant))
+	}
+	if h.Config.Server.Readiness.CheckDependencies {
+		for name, url := range dependencies {
+			checks[name] = h.checkReachable(ctx, url)
+		}
+	}
+
+	result := readiness{Ready: true, Checks: make(map[string]string, len(checks))}
+	for name, err := range checks {
+		if err != nil {
+			h.Logger.WarnContext(ctx, "Readiness check failed", "check", name, "err", err)
+			result.Ready = false
+			result.Checks[name] = err.Error()
+			continue
+		}
+		result.Checks[name] = "ok"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !result.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(w).Encode(result)
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error writing response", "err", err)
+	}
+}
+
+// version serves the build info embedded in the binary, e.g. the commit it was built from.
+func (h HealthHandler) version(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	result := version{
+		Module:    info.Main.Path,
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+		Revision:  "",
+		Time:      "",
+		Modified:  false,
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			result.Revision = setting.Value
+		case "vcs.time":
+			result.Time = setting.Value
+		case "vcs.modified":
+			result.Modified = setting.Value == "true"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(result)
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "Error writing response", "err", err)
+	}
+}
+
+// checkCredentials adds the checks of an organization's credentials to checks, with names ending in suffix.
+func checkCredentials(checks map[string]error, suffix string, conf config.Config) {
+	checks["twilio"+suffix] = required(conf.Twilio.AccountSID != "" && conf.Twilio.AuthToken != "", "credentials")
+	checks["sendgrid"+suffix] = required(conf.Mail.SendGrid.APIKey != "", "API key")
+}
+
+// checkReachable checks that an API responds, whatever its response.
+func (h HealthHandler) checkReachable(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, h.Config.Server.Readiness.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unreachable: %w", err)
+	}
+	return res.Body.Close() //nolint:wrapcheck
+}
+
+func required(set bool, what string) error {
+	if !set {
+		return errors.New(what + " not configured")
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package handler
+
+import "net/http"
+
+// RecoverPanics exposes recoverPanics to tests, so that they can recover from a handler made to panic.
+func (mf MuxFactory) RecoverPanics(h http.Handler, voicemailRoutes map[string]bool, actionStartVoicemail string) http.Handler {
+	return mf.recoverPanics(h, voicemailRoutes, actionStartVoicemail)
+}
@@ -3,41 +3,108 @@ package handler
 
 import (
 	"net/http"
+
+	"github.com/infotecho/ocomms/internal/audio"
+	"github.com/infotecho/ocomms/internal/callback"
+	"github.com/infotecho/ocomms/internal/metrics"
 )
 
 const (
 	voiceAcceptCall       = "/voice/accept-call"
 	voiceConfirmConnected = "/voice/confirm-connected"
 	voiceConnectAgent     = "/voice/connect-agent"
+	voiceAgentStatus      = "/voice/agent-status"
+	voiceBridgeCallback   = "/voice/bridge-callback"
+	voiceCallbackNumber   = "/voice/callback-number"
+	voiceCallbackSlot     = "/voice/callback-slot"
 	voiceDialOut          = "/voice/dial-out"
 	voiceEndCall          = "/voice/end-call"
+	voiceEndConference    = "/voice/end-conference"
+	voiceRoute            = "/voice/route"
+	voiceScreen           = "/voice/screen"
+	voiceSetPresence      = "/voice/set-presence"
+	voiceTransfer         = "/voice/transfer"
+	voiceTransferMenu     = "/voice/transfer-menu"
+	voiceVerifyPIN        = "/voice/verify-pin"
+	voiceVoicemails       = "/voice/voicemails"
 	voicemailStart        = "/voice/start-voicemail"
 	voicemailEnd          = "/voice/end-voicemail"
 )
 
 // MuxFactory is responsible for creating the app's HTTP request multiplexer.
 type MuxFactory struct {
+	Audit      *AuditHandler
+	Audio      *audio.Library
+	Callbacks  *CallbacksHandler
+	Export     *ExportHandler
+	Health     *HealthHandler
+	Metrics    *metrics.Metrics
 	Recordings *RecordingsHandler
+	Reports    *ReportsHandler
 	SMS        *SMSHandler
 	Voice      *VoiceHandler
 }
 
 // Mux creates the app's HTTP request multiplexer.
-func (mf MuxFactory) Mux() *http.ServeMux {
+func (mf MuxFactory) Mux() http.Handler {
 	mux := http.NewServeMux()
+	ringActions := ringActions{
+		acceptCall:     voiceAcceptCall,
+		agentStatus:    voiceAgentStatus,
+		endCall:        voiceEndCall,
+		endConference:  voiceEndConference,
+		startVoicemail: voicemailStart,
+	}
 
 	mux.Handle("/sms/inbound", mf.SMS.inbound())
 
-	mux.HandleFunc("/voice/inbound", mf.Voice.inbound(voiceDialOut, voiceConnectAgent))
-	mux.HandleFunc(voiceDialOut, mf.Voice.dialOut())
-	mux.HandleFunc(voiceConnectAgent, mf.Voice.connectAgent(voiceConnectAgent, voiceAcceptCall, voiceEndCall))
+	mux.HandleFunc("/voice/inbound", mf.Voice.inbound(voiceDialOut, voiceConnectAgent, voiceScreen, voiceVerifyPIN))
+	mux.HandleFunc(voiceScreen, mf.Voice.screen(voiceConnectAgent))
+	mux.HandleFunc(voiceVerifyPIN, mf.Voice.verifyPIN(voiceDialOut))
+	mux.HandleFunc(voiceDialOut, mf.Voice.dialOut(voiceDialOut, voiceSetPresence, voiceVoicemails))
+	mux.HandleFunc(voiceVoicemails, mf.Voice.voicemails(voiceVoicemails))
+	mux.HandleFunc(voiceSetPresence, mf.Voice.setPresence(voiceSetPresence))
+	mux.HandleFunc(voiceConnectAgent, mf.Voice.connectAgent(voiceConnectAgent, voiceRoute, ringActions))
+	mux.HandleFunc(voiceRoute, mf.Voice.route(voiceRoute, ringActions))
 	mux.HandleFunc(voiceAcceptCall, mf.Voice.acceptCall(voiceConfirmConnected))
-	mux.HandleFunc(voiceConfirmConnected, mf.Voice.confirmConnected())
-	mux.HandleFunc(voiceEndCall, mf.Voice.endCall(voicemailStart))
-	mux.HandleFunc(voicemailStart, mf.Voice.startVoicemail(voicemailStart, voicemailEnd))
+	mux.HandleFunc(voiceConfirmConnected, mf.Voice.confirmConnected(voiceTransferMenu))
+	mux.HandleFunc(voiceTransferMenu, mf.Voice.transferMenu(voiceTransfer))
+	mux.HandleFunc(voiceTransfer, mf.Voice.transfer(voiceTransferMenu, ringActions))
+	mux.HandleFunc(voiceAgentStatus, mf.Voice.agentStatus())
+	mux.HandleFunc(voiceEndConference, mf.Voice.endConference())
+	mux.HandleFunc(voiceEndCall, mf.Voice.endCall(ringActions))
+	mux.HandleFunc(voiceBridgeCallback, mf.Voice.bridgeCallback(voiceBridgeCallback))
+	mux.HandleFunc(voiceCallbackNumber, mf.Voice.callbackNumber(voiceCallbackNumber, voiceCallbackSlot, voicemailStart))
+	mux.HandleFunc(voiceCallbackSlot, mf.Voice.callbackSlot(voiceCallbackSlot, voicemailStart))
+	mux.HandleFunc(voicemailStart, mf.Voice.startVoicemail(voicemailStart, voicemailEnd, voiceCallbackNumber))
 	mux.HandleFunc(voicemailEnd, mf.Voice.endVoicemail(voicemailEnd))
 
 	mux.HandleFunc("/recordings/{id}", mf.Recordings.getRecording)
+	mux.Handle("GET "+audio.Path, mf.Audio.Handler())
+	mux.Handle("GET "+metrics.Path, adminOnly(mf.Callbacks.Config, mf.Metrics.Handler()))
+
+	mux.HandleFunc("GET /healthz", mf.Health.healthz)
+	mux.HandleFunc("GET /readyz", mf.Health.readyz)
+	mux.HandleFunc("GET /version", mf.Health.version)
+
+	mux.HandleFunc("GET "+callback.Path, mf.Callbacks.confirm)
+	mux.HandleFunc("POST "+callback.Path, mf.Callbacks.call(voiceBridgeCallback))
+	mux.HandleFunc("POST /admin/callbacks", mf.Callbacks.apiCall(voiceBridgeCallback))
+	mux.HandleFunc("POST /admin/scheduled-callbacks", mf.Callbacks.launchScheduled(voiceBridgeCallback))
+	mux.HandleFunc("GET /admin/calls/{callSid}/timeline", mf.Audit.timeline)
+	mux.HandleFunc("POST /admin/reports/weekly", mf.Reports.weekly)
+	mux.HandleFunc("GET /admin/export/{kind}", mf.Export.export)
+
+	// webhooks of callers who haven't reached an agent or voicemail yet
+	voicemailRoutes := map[string]bool{
+		"/voice/inbound":    true,
+		voiceScreen:         true,
+		voiceConnectAgent:   true,
+		voiceRoute:          true,
+		voiceEndCall:        true,
+		voiceCallbackNumber: true,
+		voiceCallbackSlot:   true,
+	}
 
-	return mux
+	return mf.recoverPanics(mux, voicemailRoutes, voicemailStart)
 }
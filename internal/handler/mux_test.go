@@ -1,10 +1,13 @@
 package handler_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/hmac"
 	"crypto/sha1" //nolint:gosec
 	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
 	"encoding/xml"
 	"flag"
 	"fmt"
@@ -14,29 +17,56 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/infotecho/ocomms/internal/audio"
+	"github.com/infotecho/ocomms/internal/audit"
+	"github.com/infotecho/ocomms/internal/callback"
+	"github.com/infotecho/ocomms/internal/callers"
+	"github.com/infotecho/ocomms/internal/conference"
 	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/export"
 	"github.com/infotecho/ocomms/internal/fakes"
 	"github.com/infotecho/ocomms/internal/handler"
+	"github.com/infotecho/ocomms/internal/history"
 	"github.com/infotecho/ocomms/internal/i18n"
 	"github.com/infotecho/ocomms/internal/mail"
+	"github.com/infotecho/ocomms/internal/metrics"
+	"github.com/infotecho/ocomms/internal/presence"
+	"github.com/infotecho/ocomms/internal/screening"
+	"github.com/infotecho/ocomms/internal/store"
 	"github.com/infotecho/ocomms/internal/twigen"
+	"github.com/infotecho/ocomms/internal/voicemail"
 	"github.com/twilio/twilio-go/client"
 	"golang.org/x/tools/txtar"
 )
 
 const (
-	clientDID  = "+17052223434" // An arbitrary DID
-	agentDID   = "+17778889999"
-	companyDID = "+16137775650"
-	authToken  = "193df2b5c93ee691ddd10c222b1a50ae" //nolint:gosec // fake auth token
+	clientDID    = "+17052223434" // An arbitrary DID
+	agentDID     = "+17778889999"
+	agentDeskDID = "+16137775651"
+	salesDID     = "+17778880000"
+	companyDID   = "+16137775650"
+	salesLineDID = "+16137775652"
+	callerSid    = "CA00000000000000000000000000000000"
+	baseURL      = "https://ocomms.example.com"
+	agentPIN     = "2468"
+	authToken    = "193df2b5c93ee691ddd10c222b1a50ae" //nolint:gosec // fake auth token
+	// call back slots on Monday 2030-01-07 at 15:00 and Tuesday 2030-01-08 at 9:30, Toronto time
+	callbackSlots = "1894046400%2C1894113000"
 )
 
-var update = flag.Bool("update", false, "rewrite testdata golden files")
+var (
+	update        = flag.Bool("update", false, "rewrite testdata golden files")
+	callbackSigRe = regexp.MustCompile(`expires=\d+&sig=[\w-]+`)
+)
 
 type XMLElement struct {
 	XMLName  xml.Name     `xml:""`
@@ -57,13 +87,60 @@ func (e *XMLElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error
 		return e.Attrs[i].Name.Local < e.Attrs[j].Name.Local
 	})
 
-	if len(e.Elements) > 0 {
+	switch {
+	case mixedContent(e.Content):
+		e.Elements = nil // keep text between SSML tags in <Say> as is
+	case len(e.Elements) > 0:
 		e.Content = ""
 	}
 	return nil
 }
 
-func setupMux(t *testing.T, sgFake *fakes.SendGridClient) *http.ServeMux {
+// mixedContent reports whether innerxml contains text alongside child elements.
+func mixedContent(innerxml string) bool {
+	decoder := xml.NewDecoder(strings.NewReader(innerxml))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && strings.TrimSpace(string(token)) != "" {
+				return true
+			}
+		}
+	}
+}
+
+func setupMux(t *testing.T, sgFake *fakes.SendGridClient, configure ...func(*config.Config)) http.Handler {
+	t.Helper()
+
+	return setupMuxWithCalls(t, sgFake, &fakes.TwilioCalls{}, configure...)
+}
+
+func setupMuxWithCalls(
+	t *testing.T,
+	sgFake *fakes.SendGridClient,
+	callsFake *fakes.TwilioCalls,
+	configure ...func(*config.Config),
+) http.Handler {
+	t.Helper()
+
+	return setupMuxFactory(t, sgFake, callsFake, configure...).Mux()
+}
+
+func setupMuxFactory(
+	t *testing.T,
+	sgFake *fakes.SendGridClient,
+	callsFake *fakes.TwilioCalls,
+	configure ...func(*config.Config),
+) *handler.MuxFactory {
 	t.Helper()
 
 	logger := slog.Default()
@@ -72,9 +149,17 @@ func setupMux(t *testing.T, sgFake *fakes.SendGridClient) *http.ServeMux {
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	config.Twilio.AgentDIDs = []string{agentDID}
+	config.Agents = append(config.Agents[:0:0], testAgent(), salesAgent()) // a new array, leaving the loaded roster alone
+	config.DIDs = []string{companyDID}
+	config.Server.BaseURL = baseURL
+	config.Storage.Driver = store.StorageDriverMemory // fresh state for each test
+	for _, c := range configure {
+		c(&config)
+	}
 
-	i18n, err := i18n.NewMessageProvider(logger, config)
+	metrics := metrics.New()
+
+	i18n, err := i18n.NewMessageProvider(logger, config, metrics)
 	if err != nil {
 		t.Fatalf("Error loading i18n dependency: %v", err)
 	}
@@ -83,50 +168,186 @@ func setupMux(t *testing.T, sgFake *fakes.SendGridClient) *http.ServeMux {
 		Config:         config,
 		I18n:           i18n,
 		Logger:         logger,
+		Metrics:        metrics,
 		SendGridClient: sgFake,
 	}
 
+	store, err := store.New(config)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+
 	requestValidator := client.NewRequestValidator(authToken)
 	handlerFactory := &handler.TwimlHandlerFactory{
 		Logger:           logger,
+		Metrics:          metrics,
 		RequestValidator: &requestValidator,
 	}
 
+	audioLibrary := &audio.Library{
+		FS: fstest.MapFS{"en/please-hold.mp3": &fstest.MapFile{Data: []byte("ID3")}},
+	}
+
+	schedule := &callback.Schedule{
+		Store: store,
+	}
+
+	trail := audit.NewTrail(config, logger, store)
+
+	callHistory := &history.Log{
+		Store:     store,
+		Retention: config.History.Retention,
+	}
+
+	voicemails := &voicemail.Box{
+		Recordings: callsFake,
+		Store:      store,
+	}
+
 	muxFactory := &handler.MuxFactory{
+		Audit: &handler.AuditHandler{
+			Config: config,
+			Logger: logger,
+			Trail:  trail,
+		},
+		Audio:   audioLibrary,
+		Metrics: metrics,
+		Callbacks: &handler.CallbacksHandler{
+			Config: config,
+			Dialer: &callback.Dialer{
+				Calls:  callsFake,
+				Config: config,
+			},
+			Logger: logger,
+			Presence: &presence.Tracker{
+				Store: store,
+			},
+			Schedule: schedule,
+		},
+		Export: &handler.ExportHandler{
+			Config: config,
+			Exporter: &export.Exporter{
+				Config:     config,
+				History:    callHistory,
+				Voicemails: voicemails,
+			},
+			I18n:   i18n,
+			Logger: logger,
+		},
+		Health: &handler.HealthHandler{
+			Config: config,
+			Logger: logger,
+		},
 		Recordings: &handler.RecordingsHandler{
 			Logger: logger,
 		},
+		Reports: &handler.ReportsHandler{
+			Config:     config,
+			History:    callHistory,
+			I18n:       i18n,
+			Logger:     logger,
+			Mailer:     mailer,
+			Voicemails: voicemails,
+		},
 		SMS: &handler.SMSHandler{
 			Config:         config,
 			HandlerFactory: handlerFactory,
+			History:        callHistory,
 			I18n:           i18n,
 			Logger:         logger,
 			Mailer:         mailer,
+			Presence: &presence.Tracker{
+				Store: store,
+			},
 		},
 		Voice: &handler.VoiceHandler{
+			Audit:     trail,
+			Callbacks: schedule,
+			Callers: &callers.Directory{
+				Store: store,
+			},
+			Conferences: &conference.Bridge{
+				Calls:  callsFake,
+				Config: config,
+				Logger: logger,
+				Store:  store,
+			},
 			Config:         config,
 			Emailer:        mailer,
 			HandlerFactory: handlerFactory,
+			History:        callHistory,
+			I18n:           i18n,
 			Logger:         logger,
-			Twigen: &twigen.Voice{
+			Metrics:        metrics,
+			Presence: &presence.Tracker{
+				Store: store,
+			},
+			Screener: &screening.Screener{
 				Config: config,
-				I18n:   i18n,
 				Logger: logger,
+				Store:  store,
+			},
+			Twigen: &twigen.Voice{
+				Audio:   audioLibrary,
+				Config:  config,
+				I18n:    i18n,
+				Logger:  logger,
+				Metrics: metrics,
 			},
+			Voicemails: voicemails,
 		},
 	}
 
-	return muxFactory.Mux()
+	return muxFactory
+}
+
+func testAgent() config.Agent {
+	var agent config.Agent
+	agent.ID = "agent"
+	agent.Name = "Agent Smith"
+	agent.Email = "agent@example.com"
+	agent.DIDs.Mobile = agentDID
+	agent.DIDs.Desk = agentDeskDID
+	agent.Languages = []string{"en", "fr"}
+	agent.Skills = []string{"support"}
+	agent.Notifications.TextMessage = true
+	agent.Notifications.Voicemail = true
+	agent.Notifications.ScheduledCallback = true
+
+	return agent
+}
+
+// salesAgent is an English-only agent, to test routing by language and skill.
+func salesAgent() config.Agent {
+	var agent config.Agent
+	agent.ID = "sales"
+	agent.Name = "Sally Sales"
+	agent.Email = "sales@example.com"
+	agent.DIDs.Mobile = salesDID
+	agent.Languages = []string{"en"}
+	agent.Skills = []string{"sales"}
+
+	return agent
 }
 
-func getLocalizedTwiml(t *testing.T, langs []string, path string, form url.Values) []byte {
+func getLocalizedTwiml(
+	t *testing.T,
+	langs []string,
+	path string,
+	form url.Values,
+	configure ...func(*config.Config),
+) []byte {
 	t.Helper()
 
-	mux := setupMux(t, &fakes.SendGridClient{})
+	mux := setupMux(t, &fakes.SendGridClient{}, configure...)
 
 	var gotArchive txtar.Archive
 	for _, lang := range langs {
-		res := sendRequest(t, mux, path+"?lang="+lang, form)
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		res := sendRequest(t, mux, path+sep+"lang="+lang, form)
 		gotArchive.Files = append(gotArchive.Files, txtar.File{
 			Name: lang,
 			Data: res,
@@ -195,6 +416,24 @@ func twilioSignature(url string, form url.Values) string {
 	return base64.StdEncoding.EncodeToString(sum)
 }
 
+func assertGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+
+	if *update {
+		updateGolden(t, path, got)
+		return
+	}
+
+	want, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		t.Errorf("Error reading golden file: %v", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func updateGolden(t *testing.T, path string, got []byte) {
 	t.Helper()
 
@@ -214,12 +453,90 @@ func updateGolden(t *testing.T, path string, got []byte) {
 	}
 }
 
+func requirePIN(config *config.Config) {
+	config.Twilio.Outbound.PIN = agentPIN
+}
+
+func enableConference(c *config.Config) {
+	c.Twilio.Conference.Enabled = true
+}
+
+// enableSalesLine adds an English-only sales line profile, open at all times, answered by the sales agent.
+func enableSalesLine(c *config.Config) {
+	c.Profiles = append(c.Profiles, config.Profile{
+		ID:        "sales",
+		DIDs:      []string{salesLineDID},
+		Languages: []string{"en"},
+		Menu:      nil,
+		Agents:    []string{"sales"},
+		MailTo:    []string{"sales-team@example.com"},
+		Hours: []config.Hours{{
+			Days:  []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"},
+			Open:  "00:00",
+			Close: "24:00",
+		}},
+	})
+	overrideVoice(c, "en", "greetings", map[string]any{"sales": "Welcome to Infotech Ottawa sales."})
+	overrideVoice(c, "fr", "greetings", map[string]any{"sales": "Bienvenue au service des ventes d'Infotech Ottawa."})
+}
+
+func closeSalesLine(c *config.Config) {
+	for i := range c.Profiles {
+		c.Profiles[i].Hours = []config.Hours{{Days: nil, Open: "09:00", Close: "17:00"}}
+	}
+}
+
+func enableMenu(c *config.Config) {
+	c.Routing.Menu = []config.MenuOption{
+		{Digit: "1", Skill: "sales"},
+		{Digit: "2", Skill: "support"},
+	}
+	overrideVoice(c, "en", "menu", map[string]any{"sales": "For sales, press {digit}."})
+	overrideVoice(c, "fr", "menu", map[string]any{"sales": "Pour les ventes, appuyez sur le {digit}."})
+}
+
+// overrideVoice overrides a voice message in lang, keeping the overrides made by other configure funcs.
+func overrideVoice(c *config.Config, lang string, key string, value any) {
+	if c.I18N.Overrides == nil {
+		c.I18N.Overrides = map[string]any{}
+	}
+	messages, _ := c.I18N.Overrides[lang].(map[string]any)
+	if messages == nil {
+		messages = map[string]any{}
+	}
+	voice, _ := messages["voice"].(map[string]any)
+	if voice == nil {
+		voice = map[string]any{}
+	}
+	voice[key] = value
+	messages["voice"] = voice
+	c.I18N.Overrides[lang] = messages
+}
+
+// recordPleaseHold plays a recording of the hold message, which only exists in English.
+func recordPleaseHold(c *config.Config) {
+	c.I18N.Overrides = map[string]any{
+		"en": map[string]any{
+			"voice": map[string]any{"pleaseHold": "[play:please-hold.mp3] Please hold while we transfer your call."},
+		},
+		"fr": map[string]any{
+			"voice": map[string]any{"pleaseHold": "[play:please-hold.mp3] Veuillez patienter alors que nous transférons votre appel."},
+		},
+	}
+}
+
+func enableSpeech(config *config.Config) {
+	config.Twilio.Speech.Menus.Language = true
+	config.Twilio.Speech.Menus.Voicemail = true
+}
+
 var goldenTwimlTests = []struct {
-	name   string
-	path   string
-	form   url.Values
-	lang   string `exhaustruct:"optional"`
-	golden string `exhaustruct:"optional"`
+	name      string
+	path      string
+	form      url.Values
+	lang      string                   `exhaustruct:"optional"`
+	golden    string                   `exhaustruct:"optional"`
+	configure []func(c *config.Config) `exhaustruct:"optional"`
 }{
 	{
 		name: "inbound-client",
@@ -228,223 +545,761 @@ var goldenTwimlTests = []struct {
 		lang: "all",
 	},
 	{
-		name: "inbound-agent",
+		name:      "inbound-client-speech",
+		path:      "/voice/inbound",
+		form:      url.Values{},
+		lang:      "all",
+		configure: []func(c *config.Config){enableSpeech},
+	},
+	{
+		name: "inbound-blocklist",
 		path: "/voice/inbound",
 		form: url.Values{
-			"From": []string{agentDID},
+			"From": []string{"+19005550100"},
 		},
-		lang: "en",
+		lang:   "all",
+		golden: "reject",
+		configure: []func(c *config.Config){func(c *config.Config) {
+			c.Screening.Blocklist = []string{"+1900*"}
+		}},
 	},
-
 	{
-		name: "dial-out",
-		path: "/voice/dial-out",
+		name: "inbound-stir-shaken-failed",
+		path: "/voice/inbound",
 		form: url.Values{
-			"Digits": []string{clientDID},
+			"From":        []string{clientDID},
+			"StirVerstat": []string{"TN-Validation-Failed-A"},
 		},
-		lang: "all",
+		lang:   "all",
+		golden: "reject",
+		configure: []func(c *config.Config){func(c *config.Config) {
+			c.Screening.StirShaken.Enabled = true
+			c.Screening.StirShaken.Failed = "block"
+		}},
 	},
-
 	{
-		name: "connect-agent-en",
-		path: "/voice/connect-agent",
+		name: "inbound-anonymous",
+		path: "/voice/inbound",
 		form: url.Values{
-			"To":     []string{companyDID},
-			"Digits": []string{"1"},
+			"From": []string{"+266696687"},
 		},
-		lang: "en",
+		lang:   "all",
+		golden: "screen-challenge",
 	},
 	{
-		name: "connect-agent-fr",
-		path: "/voice/connect-agent",
+		name: "inbound-anonymous-sales-line",
+		path: "/voice/inbound",
 		form: url.Values{
-			"To":     []string{companyDID},
-			"Digits": []string{"2"},
+			"From": []string{"+266696687"},
+			"To":   []string{salesLineDID},
 		},
-		lang: "fr",
+		lang:      "all",
+		golden:    "screen-challenge-en",
+		configure: []func(c *config.Config){enableSalesLine},
 	},
 	{
-		name: "invalid-lang-select",
-		path: "/voice/connect-agent",
+		name: "screen-passed",
+		path: "/voice/screen",
 		form: url.Values{
-			"Digits": []string{"3"},
+			"From":   []string{clientDID},
+			"Digits": []string{"5"},
 		},
-		lang: "all",
-	},
-
-	{
-		name: "accept-call",
-		path: "/voice/accept-call",
-		form: url.Values{},
+		lang:   "all",
+		golden: "inbound-client",
 	},
-
 	{
-		name: "confirm-connected",
-		path: "/voice/confirm-connected",
-		form: url.Values{},
+		name: "screen-failed",
+		path: "/voice/screen",
+		form: url.Values{
+			"From": []string{"+266696687"},
+		},
+		lang:   "all",
+		golden: "hangup",
 	},
-
 	{
-		name: "dial-agent-busy",
-		path: "/voice/end-call",
+		name: "inbound-agent",
+		path: "/voice/inbound",
 		form: url.Values{
-			"DialCallStatus": []string{"busy"},
+			"From": []string{agentDID},
 		},
-		golden: "go-to-voicemail",
+		lang: "en",
 	},
 	{
-		name: "dial-agent-no-answer",
-		path: "/voice/end-call",
+		name: "inbound-agent-desk",
+		path: "/voice/inbound",
 		form: url.Values{
-			"DialCallStatus": []string{"no-answer"},
+			"From": []string{agentDeskDID},
 		},
-		golden: "go-to-voicemail",
+		lang:   "en",
+		golden: "inbound-agent",
 	},
 	{
-		name: "dial-agent-voicemail", // Dial connects to agent's voicemail
-		path: "/voice/end-call",
+		name: "inbound-agent-pin",
+		path: "/voice/inbound",
 		form: url.Values{
-			"DialCallStatus": []string{"completed"},
-			// DialCallStatus completed with no DialCallDuration key means the agent did not accept the call
+			"From": []string{agentDID},
 		},
-		golden: "go-to-voicemail",
+		lang:      "all",
+		configure: []func(c *config.Config){requirePIN},
 	},
 	{
-		name: "dial-agent-connected",
-		path: "/voice/end-call",
+		name: "verify-pin",
+		path: "/voice/verify-pin",
 		form: url.Values{
-			"DialCallStatus":   []string{"completed"},
-			"DialCallDuration": []string{"10"},
+			"From":   []string{agentDID},
+			"Digits": []string{agentPIN},
 		},
-		golden: "noop",
+		lang:      "en",
+		golden:    "inbound-agent",
+		configure: []func(c *config.Config){requirePIN},
 	},
 	{
-		name: "dial-agent-misc-status",
-		path: "/voice/end-call",
+		name: "verify-pin-invalid",
+		path: "/voice/verify-pin",
 		form: url.Values{
-			"DialCallStatus": []string{"someotherstatus"},
+			"From":   []string{agentDID},
+			"Digits": []string{"0000"},
 		},
-		golden: "noop",
+		lang:      "all",
+		golden:    "pin-invalid",
+		configure: []func(c *config.Config){requirePIN},
 	},
 
 	{
-		name: "start-voicemail-invalid-key",
-		path: "/voice/start-voicemail",
+		name: "dial-out",
+		path: "/voice/dial-out",
 		form: url.Values{
-			"Digits": []string{"8"},
+			"Digits": []string{clientDID},
 		},
-		golden: "go-to-voicemail",
+		lang: "all",
 	},
 	{
-		name: "record-voicemail",
-		path: "/voice/start-voicemail",
+		name: "bridge-callback", // agent answered a call back
+		path: "/voice/bridge-callback?number=%2B17052223434",
 		form: url.Values{
-			"Digits": []string{"9"},
+			"From": []string{companyDID},
+			"To":   []string{agentDID},
 		},
+		lang: "all",
 	},
-
 	{
-		name: "rerecord-voicemail",
-		path: "/voice/end-voicemail",
+		name: "bridge-callback-accepted",
+		path: "/voice/bridge-callback?number=%2B17052223434",
 		form: url.Values{
-			"Digits": []string{"9"},
+			"From":   []string{companyDID},
+			"To":     []string{agentDID},
+			"Digits": []string{"1"},
 		},
+		lang: "all",
 	},
+
 	{
-		name: "end-voicemail",
-		path: "/voice/end-voicemail",
+		name: "dial-out-nanp",
+		path: "/voice/dial-out",
 		form: url.Values{
-			"Digits": []string{"hangup"},
+			"Digits": []string{clientDID[2:]},
 		},
-		golden: "noop",
+		lang:   "all",
+		golden: "dial-out",
 	},
-
 	{
-		name: "sms-reply",
-		path: "/sms/inbound",
+		name: "dial-out-premium",
+		path: "/voice/dial-out",
 		form: url.Values{
-			"Body": []string{"Hello world"},
-			"From": []string{"1234567890"},
-			"To":   []string{"6137775650"},
+			"Digits": []string{"19005550100"},
 		},
-		lang: "all",
+		lang:   "all",
+		golden: "dial-out-rejected",
 	},
-}
-
-func TestGoldenTwiml(t *testing.T) {
-	t.Parallel()
-
-	langs := []string{"en", "fr"}
-
-	for _, test := range goldenTwimlTests {
-		t.Run(test.name, func(t *testing.T) {
-			t.Parallel()
-
-			testLangs := langs
-			if test.lang != "" {
-				testLangs = []string{test.lang}
-			}
-
-			got := getLocalizedTwiml(t, testLangs, test.path, test.form)
-
-			goldenName := test.golden
-			if test.golden == "" {
-				goldenName = test.name
-			}
-			goldenFilePath := filepath.Join("testdata", "twiml", goldenName+".golden.xml")
-
-			if *update {
-				updateGolden(t, goldenFilePath, got)
-				return
-			}
-
-			want, err := os.ReadFile(filepath.Clean(goldenFilePath))
-			if err != nil {
-				t.Errorf("Error reading golden file: %v", err)
-			}
-
-			if diff := cmp.Diff(want, got); diff != "" {
-				t.Error(diff)
-			}
-		})
-	}
-}
-
-var goldenEmailTests = []struct {
-	name      string
-	path      string
-	form      url.Values
-	emailSent bool
-}{
 	{
-		name: "rerecord",
-		path: "/voice/end-voicemail",
+		name: "dial-out-country-not-allowed",
+		path: "/voice/dial-out",
 		form: url.Values{
-			"Digits": []string{"9"},
+			"Digits": []string{"011442079460000"},
 		},
-		emailSent: false,
+		lang:   "all",
+		golden: "dial-out-rejected",
 	},
 	{
-		name: "voicemail-en",
-		path: "/voice/end-voicemail?lang=en",
+		name: "dial-out-invalid",
+		path: "/voice/dial-out",
 		form: url.Values{
-			"Digits":       []string{"hangup"},
-			"From":         []string{clientDID},
-			"RecordingSid": []string{"RE37975e538fc06fea00474b868fbcc859"},
+			"Digits": []string{"5650"},
 		},
-		emailSent: true,
+		lang:   "all",
+		golden: "dial-out-rejected",
 	},
 	{
-		name: "voicemail-fr",
-		path: "/voice/end-voicemail?lang=fr",
+		name: "dial-out-agent-menu",
+		path: "/voice/dial-out",
 		form: url.Values{
-			"Digits":       []string{"hangup"},
-			"From":         []string{clientDID},
-			"RecordingSid": []string{"RE37975e538fc06fea00474b868fbcc859"},
+			"From":   []string{agentDID},
+			"Digits": []string{"*"},
 		},
-		emailSent: true,
+		lang:   "all",
+		golden: "agent-menu",
+	},
+	{
+		name: "set-presence-away",
+		path: "/voice/set-presence",
+		form: url.Values{
+			"From":   []string{agentDID},
+			"Digits": []string{"2"},
+		},
+		lang: "all",
+	},
+	{
+		name: "set-presence-invalid",
+		path: "/voice/set-presence",
+		form: url.Values{
+			"From":   []string{agentDID},
+			"Digits": []string{"7"},
+		},
+		lang:   "all",
+		golden: "agent-menu",
+	},
+
+	{
+		name: "connect-agent-en",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To":     []string{companyDID},
+			"Digits": []string{"1"},
+		},
+		lang: "en",
+	},
+	{
+		name: "connect-agent-fr",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To":     []string{companyDID},
+			"Digits": []string{"2"},
+		},
+		lang: "fr",
+	},
+	{
+		name: "connect-agent-remembered-lang", // returning caller didn't press a key to change language
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To": []string{companyDID},
+		},
+		lang:   "fr",
+		golden: "connect-agent-fr",
+	},
+	{
+		name: "connect-agent-audio",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To":     []string{companyDID},
+			"Digits": []string{"1"},
+		},
+		lang:      "en",
+		configure: []func(c *config.Config){recordPleaseHold},
+	},
+	{
+		name: "connect-agent-audio-fallback", // no French recording, say the text instead
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To":     []string{companyDID},
+			"Digits": []string{"2"},
+		},
+		lang:      "fr",
+		golden:    "connect-agent-fr",
+		configure: []func(c *config.Config){recordPleaseHold},
+	},
+	{
+		name: "connect-agent-ssml",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To":     []string{companyDID},
+			"Digits": []string{"1"},
+		},
+		lang: "en",
+		configure: []func(c *config.Config){func(c *config.Config) {
+			c.I18N.Overrides = map[string]any{"en": map[string]any{"voice": map[string]any{
+				"pleaseHold": `Please hold<break time="500ms"/> while we transfer you to ` +
+					`<phoneme alphabet="ipa" ph="ˈɪnfoʊtɛk">Infotech</phoneme> ` +
+					`<say-as interpret-as="characters">IT</say-as> support.`,
+			}}}
+		}},
+	},
+	{
+		name: "connect-agent-speech",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To":           []string{companyDID},
+			"SpeechResult": []string{"Français."},
+			"Confidence":   []string{"0.9"},
+		},
+		lang:      "fr",
+		golden:    "connect-agent-fr",
+		configure: []func(c *config.Config){enableSpeech},
+	},
+	{
+		name: "connect-agent-speech-low-confidence",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"SpeechResult": []string{"English"},
+			"Confidence":   []string{"0.1"},
+		},
+		lang:   "all",
+		golden: "invalid-lang-select",
+	},
+	{
+		name: "change-lang-speech",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"SpeechResult": []string{"Change language"},
+			"Confidence":   []string{"0.8"},
+		},
+		lang:   "all",
+		golden: "invalid-lang-select",
+	},
+	{
+		name: "change-lang",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"Digits": []string{"*"},
+		},
+		lang:   "all",
+		golden: "invalid-lang-select",
+	},
+	{
+		name: "invalid-lang-select",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"Digits": []string{"3"},
+		},
+		lang: "all",
+	},
+
+	{
+		name: "inbound-sales-line",
+		path: "/voice/inbound",
+		form: url.Values{
+			"From": []string{clientDID},
+			"To":   []string{salesLineDID},
+		},
+		lang:      "all",
+		configure: []func(c *config.Config){enableSalesLine},
+	},
+	{
+		name: "connect-agent-sales-line",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To": []string{salesLineDID},
+		},
+		lang:      "en",
+		configure: []func(c *config.Config){enableSalesLine},
+	},
+	{
+		name: "connect-agent-sales-line-closed",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To": []string{salesLineDID},
+		},
+		lang:      "en",
+		configure: []func(c *config.Config){enableSalesLine, closeSalesLine},
+	},
+	{
+		name: "connect-agent-sales-line-fr", // French isn't offered on the sales line
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To": []string{salesLineDID},
+		},
+		lang:      "fr",
+		configure: []func(c *config.Config){enableSalesLine},
+	},
+	{
+		name: "connect-agent-menu",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"To": []string{companyDID},
+		},
+		configure: []func(c *config.Config){enableMenu},
+	},
+	{
+		name: "route-sales",
+		path: "/voice/route",
+		form: url.Values{
+			"To":     []string{companyDID},
+			"Digits": []string{"1"},
+		},
+		lang:      "en",
+		configure: []func(c *config.Config){enableMenu},
+	},
+	{
+		name: "route-support-fr",
+		path: "/voice/route",
+		form: url.Values{
+			"To":     []string{companyDID},
+			"Digits": []string{"2"},
+		},
+		lang:      "fr",
+		configure: []func(c *config.Config){enableMenu},
+	},
+	{
+		name: "route-no-selection",
+		path: "/voice/route",
+		form: url.Values{
+			"To": []string{companyDID},
+		},
+		lang:      "en",
+		golden:    "connect-agent-en",
+		configure: []func(c *config.Config){enableMenu},
+	},
+	{
+		name: "route-invalid",
+		path: "/voice/route",
+		form: url.Values{
+			"To":     []string{companyDID},
+			"Digits": []string{"9"},
+		},
+		golden:    "connect-agent-menu",
+		configure: []func(c *config.Config){enableMenu},
+	},
+
+	{
+		name: "connect-agent-conference",
+		path: "/voice/connect-agent",
+		form: url.Values{
+			"CallSid": []string{callerSid},
+			"To":      []string{companyDID},
+		},
+		configure: []func(c *config.Config){enableConference},
+	},
+	{
+		name: "accept-call-conference",
+		path: "/voice/accept-call?conference=call-" + callerSid,
+		form: url.Values{},
+	},
+	{
+		name:   "transfer-menu-ended", // caller hung up, ending the conference
+		path:   "/voice/transfer-menu?conference=call-" + callerSid,
+		form:   url.Values{},
+		lang:   "all",
+		golden: "hangup",
+	},
+	{
+		name: "transfer-return",
+		path: "/voice/transfer?conference=call-" + callerSid,
+		form: url.Values{
+			"Digits": []string{"#"},
+		},
+	},
+
+	{
+		name: "accept-call",
+		path: "/voice/accept-call",
+		form: url.Values{},
+	},
+
+	{
+		name: "confirm-connected",
+		path: "/voice/confirm-connected",
+		form: url.Values{},
+	},
+
+	{
+		name: "dial-agent-busy",
+		path: "/voice/end-call?stage=1", // last ring group
+		form: url.Values{
+			"DialCallStatus": []string{"busy"},
+		},
+		golden: "go-to-voicemail",
+	},
+	{
+		name: "dial-agent-no-answer",
+		path: "/voice/end-call?stage=1", // last ring group
+		form: url.Values{
+			"DialCallStatus": []string{"no-answer"},
+		},
+		golden: "go-to-voicemail",
+	},
+	{
+		name: "dial-agent-voicemail",    // Dial connects to agent's voicemail
+		path: "/voice/end-call?stage=1", // last ring group
+		form: url.Values{
+			"DialCallStatus": []string{"completed"},
+			// DialCallStatus completed with no DialCallDuration key means the agent did not accept the call
+		},
+		golden: "go-to-voicemail",
+	},
+	{
+		name: "dial-agent-busy-fallback-fr", // French-speaking agents didn't answer, ring everyone
+		path: "/voice/end-call",
+		form: url.Values{
+			"To":             []string{companyDID},
+			"DialCallStatus": []string{"busy"},
+		},
+		lang: "fr",
+	},
+	{
+		name: "dial-agent-busy-fallback-sales", // sales agents didn't answer, ring everyone speaking the language
+		path: "/voice/end-call?skill=sales",
+		form: url.Values{
+			"To":             []string{companyDID},
+			"DialCallStatus": []string{"no-answer"},
+		},
+		lang: "en",
+	},
+	{
+		name: "dial-agent-connected",
+		path: "/voice/end-call",
+		form: url.Values{
+			"DialCallStatus":   []string{"completed"},
+			"DialCallDuration": []string{"10"},
+		},
+		golden: "noop",
+	},
+	{
+		name: "dial-agent-busy-speech",
+		path: "/voice/end-call?stage=1", // last ring group
+		form: url.Values{
+			"DialCallStatus": []string{"busy"},
+		},
+		golden:    "go-to-voicemail-speech",
+		configure: []func(c *config.Config){enableSpeech},
+	},
+	{
+		name: "dial-agent-misc-status",
+		path: "/voice/end-call",
+		form: url.Values{
+			"DialCallStatus": []string{"someotherstatus"},
+		},
+		golden: "noop",
+	},
+
+	{
+		name: "start-voicemail-invalid-key",
+		path: "/voice/start-voicemail",
+		form: url.Values{
+			"Digits": []string{"8"},
+		},
+		golden: "go-to-voicemail",
+	},
+	{
+		name: "record-voicemail",
+		path: "/voice/start-voicemail",
+		form: url.Values{
+			"Digits": []string{"9"},
+		},
+	},
+
+	{
+		name: "record-voicemail-speech",
+		path: "/voice/start-voicemail",
+		form: url.Values{
+			"SpeechResult": []string{"I'd like to leave a message."},
+			"Confidence":   []string{"0.8"},
+		},
+		golden:    "record-voicemail",
+		configure: []func(c *config.Config){enableSpeech},
+	},
+
+	{
+		name: "rerecord-voicemail",
+		path: "/voice/end-voicemail",
+		form: url.Values{
+			"Digits": []string{"9"},
+		},
+	},
+	{
+		name: "end-voicemail",
+		path: "/voice/end-voicemail",
+		form: url.Values{
+			"Digits": []string{"hangup"},
+		},
+		golden: "noop",
+	},
+
+	{
+		name: "schedule-callback",
+		path: "/voice/start-voicemail",
+		form: url.Values{
+			"Digits": []string{"1"},
+			"From":   []string{clientDID},
+		},
+	},
+	{
+		name: "schedule-callback-anonymous",
+		path: "/voice/start-voicemail",
+		form: url.Values{
+			"Digits": []string{"1"},
+			"From":   []string{"anonymous"},
+		},
+	},
+	{
+		name: "callback-number-rejected",
+		path: "/voice/callback-number",
+		form: url.Values{
+			"Digits": []string{"9005550123"}, // premium rate number
+			"From":   []string{clientDID},
+		},
+	},
+	{
+		name: "callback-no-slots",
+		path: "/voice/callback-number",
+		form: url.Values{
+			"Digits": []string{"1"},
+			"From":   []string{clientDID},
+		},
+		configure: []func(c *config.Config){func(c *config.Config) { c.Agents = nil }},
+	},
+	{
+		name: "callback-slot-invalid-key",
+		path: "/voice/callback-slot?number=%2B17052223434&slots=" + callbackSlots,
+		form: url.Values{
+			"Digits": []string{"7"},
+		},
+	},
+	{
+		name: "callback-slot",
+		path: "/voice/callback-slot?number=%2B17052223434&slots=" + callbackSlots,
+		form: url.Values{
+			"Digits": []string{"2"},
+			"To":     []string{companyDID},
+		},
+	},
+
+	{
+		name: "sms-reply",
+		path: "/sms/inbound",
+		form: url.Values{
+			"Body": []string{"Hello world"},
+			"From": []string{"1234567890"},
+			"To":   []string{"6137775650"},
+		},
+		lang: "all",
+	},
+	{
+		name: "sms-presence-away",
+		path: "/sms/inbound",
+		form: url.Values{
+			"Body": []string{"Away"},
+			"From": []string{agentDID},
+			"To":   []string{companyDID},
+		},
+		lang: "all",
+	},
+	{
+		name: "sms-presence-dnd-fr",
+		path: "/sms/inbound",
+		form: url.Values{
+			"Body": []string{"Ne pas déranger"},
+			"From": []string{agentDID},
+			"To":   []string{companyDID},
+		},
+		lang: "all",
+	},
+}
+
+func TestGoldenTwiml(t *testing.T) {
+	t.Parallel()
+
+	langs := []string{"en", "fr"}
+
+	for _, test := range goldenTwimlTests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			testLangs := langs
+			if test.lang != "" {
+				testLangs = []string{test.lang}
+			}
+
+			got := getLocalizedTwiml(t, testLangs, test.path, test.form, test.configure...)
+
+			goldenName := test.golden
+			if test.golden == "" {
+				goldenName = test.name
+			}
+			goldenFilePath := filepath.Join("testdata", "twiml", goldenName+".golden.xml")
+
+			assertGolden(t, goldenFilePath, got)
+		})
+	}
+}
+
+var goldenEmailTests = []struct {
+	name      string
+	path      string
+	form      url.Values
+	emailSent bool
+	configure []func(c *config.Config) `exhaustruct:"optional"`
+}{
+	{
+		name: "rerecord",
+		path: "/voice/end-voicemail",
+		form: url.Values{
+			"Digits": []string{"9"},
+		},
+		emailSent: false,
+	},
+	{
+		name: "voicemail-en",
+		path: "/voice/end-voicemail?lang=en",
+		form: url.Values{
+			"Digits":       []string{"hangup"},
+			"From":         []string{clientDID},
+			"RecordingSid": []string{"RE37975e538fc06fea00474b868fbcc859"},
+		},
+		emailSent: true,
+	},
+	{
+		name: "voicemail-fr",
+		path: "/voice/end-voicemail?lang=fr",
+		form: url.Values{
+			"Digits":       []string{"hangup"},
+			"From":         []string{clientDID},
+			"RecordingSid": []string{"RE37975e538fc06fea00474b868fbcc859"},
+		},
+		emailSent: true,
+	},
+
+	{
+		name: "scheduled-callback",
+		path: "/voice/callback-slot?lang=en&number=%2B17052223434&slots=" + callbackSlots,
+		form: url.Values{
+			"Digits": []string{"1"},
+			"To":     []string{companyDID},
+		},
+		emailSent: true,
+	},
+
+	{
+		name: "sms-presence",
+		path: "/sms/inbound",
+		form: url.Values{
+			"From": []string{agentDID},
+			"To":   []string{companyDID},
+			"Body": []string{"back"},
+		},
+		emailSent: false,
+	},
+	{
+		name: "sms-sales-line",
+		path: "/sms/inbound",
+		form: url.Values{
+			"From": []string{clientDID},
+			"To":   []string{salesLineDID},
+			"Body": []string{"Hello sales"},
+		},
+		emailSent: true,
+		configure: []func(c *config.Config){enableSalesLine},
+	},
+	{
+		name: "voicemail-sales-line",
+		path: "/voice/end-voicemail?lang=en",
+		form: url.Values{
+			"Digits":       []string{"hangup"},
+			"From":         []string{clientDID},
+			"To":           []string{salesLineDID},
+			"RecordingSid": []string{"RE37975e538fc06fea00474b868fbcc859"},
+		},
+		emailSent: true,
+		configure: []func(c *config.Config){enableSalesLine},
 	},
-
 	{
 		name: "sms-reply",
 		path: "/sms/inbound",
@@ -457,47 +1312,522 @@ var goldenEmailTests = []struct {
 	},
 }
 
-func TestGoldenEmails(t *testing.T) {
+func TestGoldenEmails(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range goldenEmailTests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			sgFake := &fakes.SendGridClient{}
+			mux := setupMux(t, sgFake, test.configure...)
+
+			sendRequest(t, mux, test.path, test.form)
+
+			sentEmails := sgFake.SentEmails()
+			if test.emailSent && len(sentEmails) != 1 {
+				t.Fatalf("Expected 1 sent email but got: %d", len(sentEmails))
+			}
+			if !test.emailSent && len(sentEmails) != 0 {
+				t.Fatalf("Expected 0 sent emails but got: %d", len(sentEmails))
+			}
+			if len(sentEmails) == 0 {
+				return
+			}
+
+			filePath := filepath.Join("testdata", "email", test.name+".golden.eml")
+			// call back links expire some time after the email is sent
+			got := callbackSigRe.ReplaceAll(sentEmails[0], []byte("expires={expires}&sig={sig}"))
+
+			assertGolden(t, filePath, got)
+		})
+	}
+}
+
+func TestReturningCaller(t *testing.T) {
+	t.Parallel()
+
+	mux := setupMux(t, &fakes.SendGridClient{})
+
+	sendRequest(t, mux, "/voice/connect-agent", url.Values{
+		"From":   []string{clientDID},
+		"To":     []string{companyDID},
+		"Digits": []string{"2"},
+	})
+	got := sendRequest(t, mux, "/voice/inbound", url.Values{
+		"From": []string{clientDID},
+	})
+
+	assertGolden(t, filepath.Join("testdata", "twiml", "inbound-returning-client.golden.xml"), got)
+}
+
+func TestReturningCaller_anonymous(t *testing.T) {
+	t.Parallel()
+
+	mux := setupMux(t, &fakes.SendGridClient{})
+
+	sendRequest(t, mux, "/voice/connect-agent", url.Values{
+		"From":   []string{"+266696687"},
+		"To":     []string{companyDID},
+		"Digits": []string{"2"},
+	})
+	got := sendRequest(t, mux, "/voice/screen", url.Values{
+		"From":   []string{"+266696687"},
+		"To":     []string{companyDID},
+		"Digits": []string{"5"},
+	})
+
+	// another caller withholding their caller ID may not speak French
+	if bytes.Contains(got, []byte("Bon retour")) {
+		t.Errorf("Anonymous caller should be offered the language menu, got: %s", got)
+	}
+}
+
+func TestPINLockout(t *testing.T) {
+	t.Parallel()
+
+	mux := setupMux(t, &fakes.SendGridClient{}, requirePIN, func(c *config.Config) {
+		c.Twilio.Outbound.PINAttempts = 3
+	})
+	enterPIN := func(from string, pin string) string {
+		return string(sendRequest(t, mux, "/voice/verify-pin", url.Values{
+			"From":   []string{from},
+			"Digits": []string{pin},
+		}))
+	}
+
+	for range 2 {
+		if got := enterPIN(agentDID, "0000"); !strings.Contains(got, "Incorrect PIN") {
+			t.Errorf("Incorrect PIN: got %s", got)
+		}
+	}
+	if got := enterPIN(agentDID, agentPIN); !strings.Contains(got, "Enter the number") {
+		t.Errorf("Correct PIN after 2 incorrect ones: got %s", got)
+	}
+
+	for range 3 {
+		enterPIN(agentDID, "0000")
+	}
+	if got := enterPIN(agentDID, agentPIN); !strings.Contains(got, "Too many incorrect PINs") {
+		t.Errorf("Correct PIN after 3 incorrect ones: got %s", got)
+	}
+	if got := enterPIN(salesDID, agentPIN); !strings.Contains(got, "Enter the number") {
+		t.Errorf("Correct PIN from another agent: got %s", got)
+	}
+}
+
+func TestAgentPromptLanguage(t *testing.T) {
 	t.Parallel()
 
-	for _, test := range goldenEmailTests {
-		t.Run(test.name, func(t *testing.T) {
-			t.Parallel()
+	mux := setupMux(t, &fakes.SendGridClient{}, func(c *config.Config) {
+		c.Agents[0].Languages = []string{"fr"}
+		c.Twilio.Outbound.PIN = "1234"
+	})
+	agentCall := func(digits string) url.Values {
+		return url.Values{"Digits": []string{digits}, "From": []string{agentDID}}
+	}
 
-			sgFake := &fakes.SendGridClient{}
-			mux := setupMux(t, sgFake)
+	tests := []struct {
+		path string
+		form url.Values
+		want string
+	}{
+		{"/voice/inbound", agentCall(""), "Entrez votre NIP"},
+		{"/voice/verify-pin", agentCall("0000"), "NIP incorrect"},
+		{"/voice/verify-pin", agentCall("1234"), "Entrez le numéro"},
+		{"/voice/dial-out", agentCall("1900"), "Ce numéro ne peut pas être composé"},
+		{"/voice/dial-out", agentCall("*"), "Vous êtes présentement disponible"},
+		{"/voice/set-presence", agentCall("2"), "Vous êtes maintenant absent"},
+		{"/voice/dial-out", agentCall("0"), "Vous n&apos;avez aucun message non écouté"},
+	}
+	for _, test := range tests {
+		got := sendRequest(t, mux, test.path, test.form)
+		if !bytes.Contains(got, []byte(test.want)) {
+			t.Errorf("%s with digits %q = %s, want %q", test.path, test.form.Get("Digits"), got, test.want)
+		}
+	}
+}
 
-			sendRequest(t, mux, test.path, test.form)
+func TestAgentPresence(t *testing.T) {
+	t.Parallel()
 
-			sentEmails := sgFake.SentEmails()
-			if test.emailSent && len(sentEmails) != 1 {
-				t.Fatalf("Expected 1 sent email but got: %d", len(sentEmails))
-			}
-			if !test.emailSent && len(sentEmails) != 0 {
-				t.Fatalf("Expected 0 sent emails but got: %d", len(sentEmails))
-			}
-			if len(sentEmails) == 0 {
-				return
-			}
+	mux := setupMux(t, &fakes.SendGridClient{})
+	connectAgent := url.Values{
+		"To":     []string{companyDID},
+		"Digits": []string{"1"},
+	}
 
-			filePath := filepath.Join("testdata", "email", test.name+".golden.eml")
-			got := sentEmails[0]
+	for _, did := range []string{agentDID, salesDID} {
+		sendRequest(t, mux, "/sms/inbound", url.Values{
+			"From": []string{did},
+			"Body": []string{"away"},
+		})
+	}
+	got := sendRequest(t, mux, "/voice/connect-agent", connectAgent)
+	assertGolden(t, filepath.Join("testdata", "twiml", "connect-agent-unavailable.golden.xml"), got)
+
+	// only a keyword, optionally followed by a duration, is a command
+	for _, did := range []string{agentDID, salesDID} {
+		sendRequest(t, mux, "/sms/inbound", url.Values{
+			"From": []string{did},
+			"Body": []string{"I'll be back at 3"},
+		})
+	}
+	got = sendRequest(t, mux, "/voice/connect-agent", connectAgent)
+	assertGolden(t, filepath.Join("testdata", "twiml", "connect-agent-unavailable.golden.xml"), got)
 
-			if *update {
-				updateGolden(t, filePath, got)
-				return
-			}
+	for _, did := range []string{agentDID, salesDID} {
+		sendRequest(t, mux, "/sms/inbound", url.Values{
+			"From": []string{did},
+			"Body": []string{"Back!"},
+		})
+	}
+	got = sendRequest(t, mux, "/voice/connect-agent", connectAgent)
+	assertGolden(t, filepath.Join("testdata", "twiml", "connect-agent-available.golden.xml"), got)
+}
 
-			want, err := os.ReadFile(filepath.Clean(filePath))
-			if err != nil {
-				t.Errorf("Error reading golden file: %v", err)
-			}
+func TestConferenceTransfer(t *testing.T) {
+	t.Parallel()
 
-			if diff := cmp.Diff(want, got); diff != "" {
-				t.Error(diff)
-			}
+	callsFake := &fakes.TwilioCalls{}
+	mux := setupMuxWithCalls(t, &fakes.SendGridClient{}, callsFake, enableConference)
+	room := "?conference=call-" + callerSid + "&lang=en"
+	agentSid := func(n int) string { return fmt.Sprintf("CA%032d", n) }
+	agentCall := func(n int, did string) string {
+		return agentSid(n) + " To=" + did + " From=" + companyDID +
+			" Url=" + baseURL + "/voice/accept-call?conference=call-" + callerSid + "&lang=en" +
+			" StatusCallback=" + baseURL + "/voice/agent-status?conference=call-" + callerSid
+	}
+
+	sendRequest(t, mux, "/voice/connect-agent", url.Values{
+		"CallSid": []string{callerSid},
+		"To":      []string{companyDID},
+		"Digits":  []string{"1"},
+	})
+	got := sendRequest(t, mux, "/voice/confirm-connected"+room, url.Values{
+		"CallSid": []string{agentSid(1)},
+		"To":      []string{agentDID},
+	})
+	assertGolden(t, filepath.Join("testdata", "twiml", "join-conference.golden.xml"), got)
+
+	got = sendRequest(t, mux, "/voice/transfer-menu"+room, url.Values{
+		"CallSid": []string{agentSid(1)},
+	})
+	assertGolden(t, filepath.Join("testdata", "twiml", "transfer-menu.golden.xml"), got)
+
+	got = sendRequest(t, mux, "/voice/transfer"+room, url.Values{
+		"CallSid": []string{agentSid(1)},
+		"From":    []string{companyDID},
+		"To":      []string{agentDID},
+		"Digits":  []string{"1"},
+	})
+	assertGolden(t, filepath.Join("testdata", "twiml", "transfer-cold.golden.xml"), got)
+
+	// the transferring agent hangs up, then the agent being transferred to doesn't answer
+	for _, sid := range []string{agentSid(1), agentSid(4)} {
+		sendRequest(t, mux, "/voice/agent-status?conference=call-"+callerSid, url.Values{
+			"CallSid":    []string{sid},
+			"CallStatus": []string{"completed"},
+		})
+	}
+
+	wantCreated := []string{
+		agentCall(1, agentDID),
+		agentCall(2, agentDeskDID),
+		agentCall(3, salesDID),
+		agentCall(4, salesDID),
+	}
+	if diff := cmp.Diff(wantCreated, callsFake.CreatedCalls()); diff != "" {
+		t.Errorf("Created calls mismatch (-want +got):\n%s", diff)
+	}
+
+	wantUpdated := []string{
+		agentSid(2) + " Status=completed",
+		agentSid(3) + " Status=completed",
+		callerSid + " Url=" + baseURL + "/voice/end-call?DialCallStatus=no-answer&lang=en",
+	}
+	if diff := cmp.Diff(wantUpdated, callsFake.UpdatedCalls()); diff != "" {
+		t.Errorf("Updated calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestVoicemails(t *testing.T) {
+	t.Parallel()
+
+	callsFake := &fakes.TwilioCalls{}
+	mux := setupMuxWithCalls(t, &fakes.SendGridClient{}, callsFake)
+	golden := func(name string) string { return filepath.Join("testdata", "twiml", name+".golden.xml") }
+	// voicemails are announced with the time they were left
+	timeRe := regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}`)
+	send := func(path string, form url.Values) []byte {
+		return timeRe.ReplaceAll(sendRequest(t, mux, path, form), []byte("{time}"))
+	}
+
+	for _, voicemail := range []struct{ sid, from string }{{"RE1", clientDID}, {"RE2", "+16135550123"}} {
+		send("/voice/end-voicemail?lang=en", url.Values{
+			"Digits":       []string{"hangup"},
+			"From":         []string{voicemail.from},
+			"To":           []string{companyDID},
+			"RecordingSid": []string{voicemail.sid},
+			"RecordingUrl": []string{"https://api.twilio.com/recordings/" + voicemail.sid},
+		})
+	}
+
+	agent := func(digits string) url.Values {
+		return url.Values{"From": []string{agentDID}, "Digits": []string{digits}}
+	}
+
+	got := send("/voice/dial-out", agent("0"))
+	assertGolden(t, golden("voicemails-play"), got) // newest first
+
+	got = send("/voice/voicemails?voicemail=RE2", agent("2"))
+	assertGolden(t, golden("voicemails-handled"), got)
+
+	got = send("/voice/voicemails?voicemail=RE1", agent("4"))
+	assertGolden(t, golden("voicemails-call-back"), got)
+
+	got = send("/voice/voicemails?voicemail=RE1", agent("3"))
+	assertGolden(t, golden("voicemails-deleted"), got)
+	if diff := cmp.Diff([]string{"RE1"}, callsFake.DeletedRecordings()); diff != "" {
+		t.Errorf("deleted recordings (-want +got):\n%s", diff)
+	}
+
+	// another agent deleted the message meanwhile
+	got = send("/voice/voicemails?voicemail=RE1", agent("2"))
+	assertGolden(t, golden("voicemails-gone"), got)
+
+	got = send("/voice/dial-out", agent("0"))
+	assertGolden(t, golden("voicemails-none"), got)
+}
+
+func TestVoicemails_callBackRejected(t *testing.T) {
+	t.Parallel()
+
+	mux := setupMux(t, &fakes.SendGridClient{})
+	sendRequest(t, mux, "/voice/end-voicemail?lang=en", url.Values{
+		"Digits":       []string{"hangup"},
+		"From":         []string{"+19005550100"}, // a premium rate number agents may not dial
+		"To":           []string{companyDID},
+		"RecordingSid": []string{"RE1"},
+		"RecordingUrl": []string{"https://api.twilio.com/recordings/RE1"},
+	})
+
+	got := sendRequest(t, mux, "/voice/voicemails?voicemail=RE1", url.Values{
+		"From":   []string{agentDID},
+		"Digits": []string{"4"},
+	})
+	got = regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}`).ReplaceAll(got, []byte("{time}"))
+	assertGolden(t, filepath.Join("testdata", "twiml", "voicemails-call-back-rejected.golden.xml"), got)
+}
+
+func TestCallback(t *testing.T) {
+	t.Parallel()
+
+	callsFake := &fakes.TwilioCalls{}
+	mux := setupMuxWithCalls(t, &fakes.SendGridClient{}, callsFake, func(c *config.Config) {
+		c.Admin.Token = "admin-token"
+	})
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	conf, err := config.Load(true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	link := callback.Link(conf, companyDID, clientDID, time.Now())
+
+	rec := serve(httptest.NewRequest(http.MethodGet, link, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `value="agent"`) {
+		t.Errorf("GET call back link: %d %s", rec.Code, rec.Body.String())
+	}
+	if len(callsFake.CreatedCalls()) > 0 {
+		t.Error("Following a call back link should not call anyone")
+	}
+
+	rec = serve(httptest.NewRequest(http.MethodGet, strings.Replace(link, "From=%2B1", "From=%2B2", 1), nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GET tampered call back link: got status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, link, strings.NewReader("agent=agent"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rec = serve(req); rec.Code != http.StatusOK {
+		t.Errorf("POST call back link: got status %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, callback.Link(conf, companyDID, "+19005550100", time.Now()),
+		strings.NewReader("agent=agent"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rec = serve(req); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST call back link to a premium rate number: got status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/callbacks",
+		strings.NewReader(`{"agent": "sales", "number": "6135550123", "did": "`+companyDID+`"}`))
+	if rec = serve(req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Admin API without token: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	req.Header.Set("Authorization", "Bearer admin-token")
+	if rec = serve(req); rec.Code != http.StatusAccepted {
+		t.Errorf("Admin API: got status %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/callbacks",
+		strings.NewReader(`{"agent": "sales", "number": "6135550123", "did": "+16135550100"}`))
+	req.Header.Set("Authorization", "Bearer admin-token")
+	if rec = serve(req); rec.Code != http.StatusBadRequest {
+		t.Errorf("Admin API from unknown DID: got status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	bridge := baseURL + "/voice/bridge-callback?number="
+	wantCreated := []string{
+		"CA00000000000000000000000000000001 To=" + agentDID + " From=" + companyDID +
+			" Url=" + bridge + "%2B17052223434 StatusCallback=",
+		"CA00000000000000000000000000000002 To=" + salesDID + " From=" + companyDID +
+			" Url=" + bridge + "%2B16135550123 StatusCallback=",
+	}
+	if diff := cmp.Diff(wantCreated, callsFake.CreatedCalls()); diff != "" {
+		t.Errorf("Created calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestScheduledCallback(t *testing.T) {
+	t.Parallel()
+
+	callsFake := &fakes.TwilioCalls{}
+	mux := setupMuxWithCalls(t, &fakes.SendGridClient{}, callsFake, func(c *config.Config) {
+		c.Admin.Token = "admin-token"
+	})
+	launch := func() string {
+		req := httptest.NewRequest(http.MethodPost, "/admin/scheduled-callbacks", nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Launching scheduled call backs: got status %d", rec.Code)
+		}
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	// slots are offered from now on, every half hour since the test config has no business hours
+	got := string(sendRequest(t, mux, "/voice/callback-number?lang=en", url.Values{
+		"Digits": []string{"1"},
+		"From":   []string{clientDID},
+		"To":     []string{companyDID},
+	}))
+	slots := regexp.MustCompile(`slots=(\d+)%2C(\d+)%2C(\d+)&`).FindStringSubmatch(got)
+	if len(slots) != 4 || !strings.Contains(got, "press 3.") {
+		t.Fatalf("Expected 3 call back slots, got: %s", got)
+	}
+	for _, slot := range slots[1:] {
+		seconds, _ := strconv.ParseInt(slot, 10, 64)
+		if start := time.Unix(seconds, 0); !start.After(time.Now()) || start.Minute()%30 != 0 {
+			t.Errorf("Unexpected call back slot %v", start)
+		}
+	}
+
+	// a callback that is due, and one in the future
+	for _, request := range []struct{ sid, slots string }{
+		{"CA1", "1704726000"}, // 2024-01-08 10:00
+		{"CA2", callbackSlots},
+	} {
+		sendRequest(t, mux, "/voice/callback-slot?lang=en&number=%2B17052223434&slots="+request.slots, url.Values{
+			"CallSid": []string{request.sid},
+			"Digits":  []string{"1"},
+			"To":      []string{companyDID},
 		})
 	}
+
+	if got := launch(); got != `{"launched":1,"waiting":0}` {
+		t.Errorf("Unexpected launch result: %s", got)
+	}
+	if got := launch(); got != `{"launched":0,"waiting":0}` {
+		t.Errorf("Call backs should only be launched once: %s", got)
+	}
+
+	wantCreated := []string{
+		"CA00000000000000000000000000000001 To=" + agentDID + " From=" + companyDID +
+			" Url=" + baseURL + "/voice/bridge-callback?number=%2B17052223434 StatusCallback=",
+	}
+	if diff := cmp.Diff(wantCreated, callsFake.CreatedCalls()); diff != "" {
+		t.Errorf("Created calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	callsFake := &fakes.TwilioCalls{}
+	mux := setupMuxWithCalls(t, &fakes.SendGridClient{}, callsFake, enableConference, func(c *config.Config) {
+		c.Admin.Token = "admin-token"
+	})
+
+	sendRequest(t, mux, "/voice/connect-agent", url.Values{
+		"CallSid": []string{callerSid},
+		"To":      []string{companyDID},
+		"Digits":  []string{"2"},
+	})
+	// a returning caller's remembered language isn't chosen again
+	sendRequest(t, mux, "/voice/connect-agent?lang=fr", url.Values{
+		"CallSid": []string{fmt.Sprintf("CA%032d", 99)},
+		"To":      []string{companyDID},
+	})
+	// an agent answers, then transfers the call to another, who answers too
+	room := "?conference=call-" + callerSid + "&lang=fr"
+	sendRequest(t, mux, "/voice/confirm-connected"+room, url.Values{
+		"CallSid": []string{fmt.Sprintf("CA%032d", 1)},
+		"To":      []string{agentDID},
+	})
+	sendRequest(t, mux, "/voice/transfer"+room, url.Values{
+		"CallSid": []string{fmt.Sprintf("CA%032d", 1)},
+		"From":    []string{companyDID},
+		"To":      []string{agentDID},
+		"Digits":  []string{"1"},
+	})
+	sendRequest(t, mux, "/voice/confirm-connected"+room, url.Values{
+		"CallSid": []string{fmt.Sprintf("CA%032d", 5)}, // calls 3 and 4 rang agents for the returning caller
+		"To":      []string{salesDID},
+	})
+	sendRequest(t, mux, "/voice/end-call?lang=fr", url.Values{
+		"DialCallStatus": []string{"canceled"},
+	})
+	sendRequest(t, mux, "/voice/end-voicemail?lang=fr", url.Values{
+		"Digits":       []string{"hangup"},
+		"From":         []string{clientDID},
+		"RecordingSid": []string{"RE37975e538fc06fea00474b868fbcc859"},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/voice/inbound", nil)
+	req.Header.Set("X-Twilio-Signature", "Np1nax6uFoY6qpfT5l9jWwJeit0=")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Metrics without token: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	got := rec.Body.String()
+
+	for _, want := range []string{
+		`ocomms_languages_chosen_total{lang="fr"} 1`,
+		`ocomms_call_outcomes_total{outcome="answered"} 1`,
+		`ocomms_call_outcomes_total{outcome="abandoned"} 1`,
+		`ocomms_call_outcomes_total{outcome="voicemail"} 1`,
+		`ocomms_sendgrid_responses_total{code="0"} 1`,
+		`ocomms_twilio_signature_failures_total{route="/voice/inbound"} 1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Metrics should contain %q, got:\n%s", want, got)
+		}
+	}
 }
 
 func TestTwilioSignature(t *testing.T) {
@@ -521,3 +1851,347 @@ func TestTwilioSignature(t *testing.T) {
 		})
 	}
 }
+
+func TestRecoverPanics(t *testing.T) {
+	t.Parallel()
+
+	mf := setupMuxFactory(t, &fakes.SendGridClient{}, &fakes.TwilioCalls{})
+	panicking := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("handler bug") })
+	h := mf.RecoverPanics(panicking, map[string]bool{"/voice/connect-agent": true}, "/voice/start-voicemail")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/voice/connect-agent?lang=en", nil))
+	twiml := rec.Body.String()
+	for _, want := range []string{"technical difficulties", "<Redirect>/voice/start-voicemail?lang=en</Redirect>"} {
+		if !strings.Contains(twiml, want) {
+			t.Errorf("Response should contain %q, got:\n%s", want, twiml)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/export/calls", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Panicking API request: got status %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestNoTwilioLanguages(t *testing.T) {
+	t.Parallel()
+
+	mux := setupMux(t, &fakes.SendGridClient{}, func(c *config.Config) {
+		c.Twilio.Languages = nil
+	})
+
+	// callers are greeted in the default language
+	twiml := string(sendRequest(t, mux, "/voice/inbound", url.Values{
+		"From": []string{clientDID},
+		"To":   []string{companyDID},
+	}))
+	if !strings.Contains(twiml, "Welcome") || strings.Contains(twiml, "technical difficulties") {
+		t.Errorf("Unexpected response without Twilio languages:\n%s", twiml)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+
+	get := func(mux http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	unconfigured := setupMux(t, &fakes.SendGridClient{})
+	if rec := get(unconfigured, "/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("GET /healthz status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	rec := get(unconfigured, "/readyz")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz without credentials status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	want := `{"ready":false,"checks":{"sendgrid":"API key not configured","twilio":"credentials not configured"}}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("GET /readyz without credentials = %s, want %s", got, want)
+	}
+
+	configured := setupMux(t, &fakes.SendGridClient{}, func(c *config.Config) {
+		c.Twilio.AccountSID = "AC00000000000000000000000000000000"
+		c.Twilio.AuthToken = authToken
+		c.Mail.SendGrid.APIKey = "SG.key"
+	})
+	if rec := get(configured, "/readyz"); rec.Code != http.StatusOK {
+		t.Errorf("GET /readyz status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	withTenant := setupMux(t, &fakes.SendGridClient{}, func(c *config.Config) {
+		c.Twilio.AccountSID = "AC00000000000000000000000000000000"
+		c.Twilio.AuthToken = authToken
+		c.Mail.SendGrid.APIKey = "SG.key"
+		c.Tenants = []config.Tenant{{
+			ID:         "partner",
+			AccountSID: "AC00000000000000000000000000000001",
+			AuthToken:  "", // forgotten
+			AdminToken: "",
+			PIN:        "",
+			DIDs:       nil,
+			Agents:     nil,
+			Profiles:   nil,
+			Mail:       c.Mail,
+			Messages:   nil,
+		}}
+	})
+	rec = get(withTenant, "/readyz")
+	want = `{"ready":false,"checks":{"sendgrid":"ok","sendgrid:partner":"ok","twilio":"ok",` +
+		`"twilio:partner":"credentials not configured"}}`
+	if got := strings.TrimSpace(rec.Body.String()); rec.Code != http.StatusServiceUnavailable || got != want {
+		t.Errorf("GET /readyz with unconfigured tenant = %d %s, want %s", rec.Code, got, want)
+	}
+
+	rec = get(configured, "/version")
+	var version struct {
+		GoVersion string `json:"goVersion"`
+	}
+	err := json.Unmarshal(rec.Body.Bytes(), &version)
+	if err != nil || version.GoVersion == "" {
+		t.Errorf("GET /version = %s, want build info", rec.Body)
+	}
+}
+
+func TestAuditTimeline(t *testing.T) {
+	t.Parallel()
+
+	mux := setupMux(t, &fakes.SendGridClient{}, func(c *config.Config) {
+		c.Admin.Token = "admin-token"
+	})
+
+	call := url.Values{
+		"CallSid": []string{callerSid},
+		"From":    []string{clientDID},
+		"To":      []string{companyDID},
+	}
+	withParams := func(params ...string) url.Values {
+		values := url.Values{}
+		for k, v := range call {
+			values[k] = v
+		}
+		for i := 0; i+1 < len(params); i += 2 {
+			values.Set(params[i], params[i+1])
+		}
+		return values
+	}
+
+	sendRequest(t, mux, "/voice/inbound", call)
+	sendRequest(t, mux, "/voice/connect-agent", withParams("Digits", "2"))
+	sendRequest(t, mux, "/voice/end-call?lang=fr", withParams("DialCallStatus", "no-answer"))
+	sendRequest(t, mux, "/voice/end-voicemail?lang=fr", withParams("Digits", "9"))
+	sendRequest(t, mux, "/voice/end-voicemail?lang=fr&rerecords=1", withParams("Digits", "hangup"))
+
+	get := func(token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/calls/"+callerSid+"/timeline", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("wrong-token"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Timeline with wrong token status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec := get("admin-token")
+	var timeline struct {
+		Events []struct {
+			Kind     string            `json:"kind"`
+			Decision string            `json:"decision"`
+			Details  map[string]string `json:"details"`
+		} `json:"events"`
+	}
+	err := json.Unmarshal(rec.Body.Bytes(), &timeline)
+	if err != nil {
+		t.Fatalf("Failed to decode timeline %q: %v", rec.Body, err)
+	}
+
+	var got []string
+	for _, event := range timeline.Events {
+		got = append(got, event.Kind+": "+event.Decision)
+	}
+	want := []string{
+		"screening: allow",
+		"language: fr",
+		"business-hours: open",
+		"ring: ring-group",
+		"dial-status: next-group",
+		"ring: ring-group", // everyone else
+		"voicemail: rerecord",
+		"voicemail: left",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Timeline mismatch (-want +got):\n%s", diff)
+	}
+	if n := len(timeline.Events); n > 0 && timeline.Events[n-1].Details["rerecords"] != "1" {
+		t.Errorf("Voicemail left details = %v, want 1 rerecord", timeline.Events[n-1].Details)
+	}
+}
+
+func TestWeeklyReport(t *testing.T) {
+	t.Parallel()
+
+	sgFake := &fakes.SendGridClient{}
+	reportsWith := func(driver string) func(*config.Config) {
+		return func(c *config.Config) {
+			c.Admin.Token = "admin-token"
+			c.Reports.Weekly.MailTo = []string{"management@example.com"}
+			c.Storage.Driver = driver
+			c.Storage.Dir = t.TempDir()
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/admin/reports/weekly", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	setupMux(t, sgFake, reportsWith(store.StorageDriverMemory)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Weekly report with memory storage status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	mux := setupMux(t, sgFake, reportsWith(store.StorageDriverFile))
+
+	post := func(token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/reports/weekly", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("wrong-token"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Weekly report with wrong token status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if n := len(sgFake.SentEmails()); n != 0 {
+		t.Fatalf("Expected 0 sent emails without authorization but got: %d", n)
+	}
+
+	rec = post("admin-token")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Weekly report status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	sentEmails := sgFake.SentEmails()
+	if len(sentEmails) != 1 {
+		t.Fatalf("Expected 1 sent email but got: %d", len(sentEmails))
+	}
+	// today's activity is in next week's report
+	for _, want := range []string{"To:  <management@example.com>", "Calls: 0 (0 more blocked)", "Answer rate: 0%"} {
+		if !bytes.Contains(sentEmails[0], []byte(want)) {
+			t.Errorf("Weekly report email missing %q:\n%s", want, sentEmails[0])
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	mux := setupMux(t, &fakes.SendGridClient{}, func(c *config.Config) {
+		c.Admin.Token = "admin-token"
+	})
+
+	call := url.Values{
+		"CallSid": []string{callerSid},
+		"From":    []string{clientDID},
+		"To":      []string{companyDID},
+	}
+	sendRequest(t, mux, "/voice/inbound", call)
+	sendRequest(t, mux, "/voice/connect-agent", url.Values{
+		"CallSid": []string{callerSid},
+		"Digits":  []string{"1"},
+		"From":    []string{clientDID},
+		"To":      []string{companyDID},
+	})
+	sendRequest(t, mux, "/voice/end-voicemail?lang=en", url.Values{
+		"CallSid":      []string{callerSid},
+		"Digits":       []string{"hangup"},
+		"From":         []string{clientDID},
+		"RecordingSid": []string{"RE00000000000000000000000000000000"},
+		"To":           []string{companyDID},
+	})
+	sendRequest(t, mux, "/sms/inbound", url.Values{
+		"Body":       []string{"=1+1 Hello world"},
+		"From":       []string{clientDID},
+		"MessageSid": []string{"SM00000000000000000000000000000000"},
+		"To":         []string{companyDID},
+	})
+
+	// a range around today in any time zone
+	now := time.Now().UTC()
+	days := "from=" + now.AddDate(0, 0, -1).Format("2006-01-02") + "&to=" + now.AddDate(0, 0, 1).Format("2006-01-02")
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path+"&"+days, nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{
+			// phone numbers are redacted by default, and cells spreadsheets would run as formulas are escaped
+			path:   "/admin/export/calls?columns=callSid,from,lang,outcome",
+			status: http.StatusOK,
+			want:   "callSid,from,lang,outcome\n" + callerSid + ",+*******3434,en,voicemail\n",
+		},
+		{
+			path:   "/admin/export/voicemails?columns=from,recordingSid,status&redact=false",
+			status: http.StatusOK,
+			want:   "from,recordingSid,status\n" + clientDID + ",RE00000000000000000000000000000000,unhandled\n",
+		},
+		{
+			path:   "/admin/export/messages?columns=from,body&redact=true",
+			status: http.StatusOK,
+			want:   "from,body\n+*******3434,'=1+1 Hello world\n",
+		},
+		{
+			path:   "/admin/export/messages?format=ndjson&columns=from,body",
+			status: http.StatusOK,
+			want:   `{"body":"=1+1 Hello world","from":"+*******3434"}` + "\n",
+		},
+		{
+			path:   "/admin/export/messages?columns=from&redact=no",
+			status: http.StatusBadRequest,
+			want:   "",
+		},
+		{
+			path:   "/admin/export/calls?columns=duration",
+			status: http.StatusBadRequest,
+			want:   "",
+		},
+		{
+			path:   "/admin/export/faxes?format=csv",
+			status: http.StatusBadRequest,
+			want:   "",
+		},
+	}
+
+	for _, test := range tests {
+		rec := get(test.path)
+		if rec.Code != test.status {
+			t.Errorf("GET %s status = %d, want %d: %s", test.path, rec.Code, test.status, rec.Body)
+			continue
+		}
+		if test.want == "" {
+			continue
+		}
+		if diff := cmp.Diff(test.want, rec.Body.String()); diff != "" {
+			t.Errorf("GET %s mismatch (-want +got):\n%s", test.path, diff)
+		}
+	}
+
+	// unredacted numbers read back as they were recorded, e.g. to call clients from a spreadsheet
+	records, err := csv.NewReader(get("/admin/export/calls?columns=from,to&redact=false").Body).ReadAll()
+	if diff := cmp.Diff([][]string{{"from", "to"}, {clientDID, companyDID}}, records); err != nil || diff != "" {
+		t.Errorf("Unredacted CSV export mismatch (-want +got), err %v:\n%s", err, diff)
+	}
+}
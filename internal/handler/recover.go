@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// recoverPanics responds to webhooks whose handler panicked with TwiML apologizing to the caller,
+// so that they never hear Twilio's "application error" message.
+// Callers still being connected to an agent are then offered to leave a voicemail.
+func (mf MuxFactory) recoverPanics(
+	h http.Handler,
+	voicemailRoutes map[string]bool,
+	actionStartVoicemail string,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered) // aborts the response, as intended
+			}
+
+			ctx := r.Context()
+			mf.Voice.Logger.ErrorContext(
+				ctx,
+				"Recovered from panic in handler",
+				"err", fmt.Sprint(recovered),
+				"route", r.Pattern,
+				"stack", string(debug.Stack()),
+			)
+
+			if !strings.HasPrefix(r.URL.Path, "/voice/") {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			action := ""
+			if voicemailRoutes[r.URL.Path] {
+				action = actionStartVoicemail
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			_, err := w.Write([]byte(mf.Voice.Twigen.Apologize(ctx, action, r.URL.Query().Get("lang"))))
+			if err != nil {
+				mf.Voice.Logger.ErrorContext(ctx, "Error writing response", "err", err)
+			}
+		}()
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/history"
+	"github.com/infotecho/ocomms/internal/i18n"
+	"github.com/infotecho/ocomms/internal/mail"
+	"github.com/infotecho/ocomms/internal/report"
+	"github.com/infotecho/ocomms/internal/store"
+	"github.com/infotecho/ocomms/internal/voicemail"
+)
+
+// ReportsHandler emails activity reports to management through the admin API.
+type ReportsHandler struct {
+	Config     config.Config
+	History    *history.Log
+	I18n       *i18n.MessageProvider
+	Logger     *slog.Logger
+	Mailer     *mail.SendGridMailer
+	Voicemails *voicemail.Box
+}
+
+// weekly emails a summary of the 7 days before today, e.g. every Monday from Cloud Scheduler,
+// and responds with the summary.
+func (h ReportsHandler) weekly(w http.ResponseWriter, r *http.Request) {
+	if !adminAuthorized(h.Config, r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	if h.Config.Storage.Driver == store.StorageDriverMemory {
+		// each instance would report its own history since it started, and none after scaling to zero
+		h.Logger.ErrorContext(ctx, "Weekly reports need persistent storage, the memory driver loses history")
+		http.Error(w, "weekly reports need persistent storage", http.StatusServiceUnavailable)
+		return
+	}
+
+	from, to := report.LastWeek(h.I18n.Local(time.Now()))
+
+	calls, err := h.History.Calls(ctx, from, to)
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error getting call history", "err", err)
+		http.Error(w, "failed to get call history", http.StatusInternalServerError)
+		return
+	}
+	messages, err := h.History.Messages(ctx, from, to)
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error getting message history", "err", err)
+		http.Error(w, "failed to get message history", http.StatusInternalServerError)
+		return
+	}
+
+	unhandled := func(recordingSid string) bool {
+		vm, ok, err := h.Voicemails.Get(ctx, recordingSid)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error getting voicemail", "err", err)
+			return true
+		}
+		return ok && !vm.Handled // deleted voicemails were taken care of
+	}
+	summary := report.Summarize(from, to, from.Location(), calls, messages, unhandled)
+	h.Mailer.WeeklyReport(ctx, h.Config.Reports.Weekly.Lang, summary)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(summary)
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error writing response", "err", err)
+	}
+}
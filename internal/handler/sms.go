@@ -3,11 +3,20 @@ package handler
 import (
 	"context"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/infotecho/ocomms/internal/agents"
 	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/history"
 	"github.com/infotecho/ocomms/internal/i18n"
+	"github.com/infotecho/ocomms/internal/log"
 	"github.com/infotecho/ocomms/internal/mail"
+	"github.com/infotecho/ocomms/internal/presence"
+	"github.com/infotecho/ocomms/internal/profiles"
 	"github.com/twilio/twilio-go/twiml"
 )
 
@@ -16,8 +25,10 @@ type SMSHandler struct {
 	Config         config.Config
 	I18n           *i18n.MessageProvider
 	HandlerFactory *TwimlHandlerFactory
+	History        *history.Log
 	Logger         *slog.Logger
 	Mailer         *mail.SendGridMailer
+	Presence       *presence.Tracker
 }
 
 // inbound implements the Twilio incoming message webhook.
@@ -26,22 +37,121 @@ func (h SMSHandler) inbound() http.HandlerFunc {
 		from := params["From"]
 		body := params["Body"]
 
-		h.Mailer.TextMessage(ctx, h.Config.I18N.DefaultLang, from, body)
-
-		replyBodyEn := h.I18n.Message(ctx, "en", func(m i18n.Messages) string { return m.Messaging.Response })
-		replyBodyFr := h.I18n.Message(ctx, "fr", func(m i18n.Messages) string { return m.Messaging.Response })
-		replyBody := replyBodyEn + "\n" + replyBodyFr
+		if agent, ok := agents.ByDID(h.Config.Agents, from); ok {
+			if reply, ok := h.updatePresence(ctx, agent, body); ok {
+				return h.reply(ctx, reply)
+			}
+		}
 
-		twiml, err := twiml.Messages([]twiml.Element{
-			&twiml.MessagingMessage{
-				Body: replyBody,
-			},
+		err := h.History.AddMessage(ctx, history.Message{
+			MessageSid: params["MessageSid"],
+			From:       from,
+			To:         params["To"],
+			Time:       time.Now(),
+			Body:       body,
 		})
 		if err != nil {
-			h.Logger.ErrorContext(ctx, "Error generating TWiML", "err", err)
-			return ""
+			// the message is only kept in the log, and the notification email if sent
+			h.Logger.ErrorContext(ctx, "Error recording message history",
+				"err", err, "from", from, log.MessageBodyKey, body)
+		}
+
+		profile := profiles.Select(h.Config, params["To"])
+		h.Mailer.TextMessage(ctx, h.Config.I18N.DefaultLang, profile, from, body)
+
+		// reply in each language of the texted company DID's profile
+		replies := make([]string, len(profile.Languages))
+		for i, lang := range profile.Languages {
+			replies[i] = h.I18n.Message(ctx, lang, func(m i18n.Messages) string { return m.Messaging.Response })
+		}
+
+		return h.reply(ctx, strings.Join(replies, "\n"))
+	})
+}
+
+// updatePresence changes an agent's availability if their text message is a presence command,
+// such as "away" or "back". Commands may end with a duration after which the agent is available again, e.g. "away 3d".
+// Anything else makes the message an ordinary text, e.g. "I'll be back at 3".
+// Returns the confirmation to reply with, in the language of the command, and whether the message was a command.
+func (h SMSHandler) updatePresence(ctx context.Context, agent config.Agent, body string) (string, bool) {
+	command := body
+	var duration time.Duration
+	if fields := strings.Fields(body); len(fields) > 1 {
+		d, err := presence.ParseDuration(fields[len(fields)-1])
+		if err == nil {
+			command, duration = strings.Join(fields[:len(fields)-1], " "), d
+		}
+	}
+
+	for _, lang := range slices.Sorted(maps.Keys(h.Config.Twilio.Languages)) {
+		status, ok := h.presenceCommand(ctx, lang, command)
+		if !ok {
+			continue
+		}
+
+		state := presence.State{Status: status, Until: time.Time{}}
+		if status != presence.StatusAvailable && duration > 0 {
+			state.Until = time.Now().Add(duration)
+		}
+
+		err := h.Presence.SetState(ctx, agent.ID, state)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error setting agent presence", "err", err)
+			return "", false
+		}
+
+		until := ""
+		if !state.Until.IsZero() {
+			until = h.I18n.MessageReplace(ctx, lang,
+				func(m i18n.Messages) string { return m.Messaging.Presence.Until },
+				map[string]string{"time": h.I18n.FormatTime(state.Until)},
+			)
 		}
 
-		return twiml
+		reply := h.I18n.MessageReplace(ctx, lang,
+			func(m i18n.Messages) string { return m.Messaging.Presence.Updated },
+			map[string]string{
+				"status": h.I18n.Message(ctx, lang, func(m i18n.Messages) string { return m.PresenceStatus(status) }),
+				"until":  until,
+			},
+		)
+		return reply, true
+	}
+
+	return "", false
+}
+
+// presenceCommand returns the presence status requested by a command in lang, if any.
+func (h SMSHandler) presenceCommand(ctx context.Context, lang string, text string) (presence.Status, bool) {
+	commands := []struct {
+		status   presence.Status
+		keywords func(m i18n.Messages) string
+	}{
+		{presence.StatusDND, func(m i18n.Messages) string { return m.Messaging.Presence.Keywords.DND }},
+		{presence.StatusAway, func(m i18n.Messages) string { return m.Messaging.Presence.Keywords.Away }},
+		{presence.StatusAvailable, func(m i18n.Messages) string { return m.Messaging.Presence.Keywords.Available }},
+	}
+
+	for _, command := range commands {
+		keywords := i18n.Keywords(h.I18n.Message(ctx, lang, command.keywords))
+		if i18n.IsKeyword(text, keywords) {
+			return command.status, true
+		}
+	}
+
+	return "", false
+}
+
+func (h SMSHandler) reply(ctx context.Context, body string) string {
+	twiml, err := twiml.Messages([]twiml.Element{
+		&twiml.MessagingMessage{
+			Body: body,
+		},
 	})
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error generating TWiML", "err", err)
+		return ""
+	}
+
+	return twiml
 }
@@ -5,12 +5,15 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/infotecho/ocomms/internal/log"
+	"github.com/infotecho/ocomms/internal/metrics"
 	"github.com/twilio/twilio-go/client"
 )
 
 // TwimlHandlerFactory creates http.HandleFunc instances for Twilio webhook handlers.
 type TwimlHandlerFactory struct {
 	Logger           *slog.Logger
+	Metrics          *metrics.Metrics
 	RequestValidator *client.RequestValidator
 }
 
@@ -32,11 +35,21 @@ func (f TwimlHandlerFactory) handler(
 
 		url := "https://" + r.Host + r.URL.String()
 		signature := r.Header.Get("X-Twilio-Signature")
-		if !f.RequestValidator.Validate(url, params, signature) {
+		valid := f.RequestValidator.Validate(url, params, signature)
+		log.Annotate(r.Context(), slog.Bool("signatureValid", valid))
+		if !valid {
+			f.Metrics.SignatureFailure(r.Pattern)
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
+		// state carried between hooks in action URL query strings, never overriding Twilio's parameters
+		for k, v := range r.URL.Query() {
+			if _, ok := params[k]; !ok {
+				params[k] = v[0]
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/xml")
 
 		lang := r.URL.Query().Get("lang")
@@ -2,116 +2,796 @@ package handler
 
 import (
 	"context"
+	"crypto/subtle"
+	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"net/url"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/infotecho/ocomms/internal/agents"
+	"github.com/infotecho/ocomms/internal/audit"
+	"github.com/infotecho/ocomms/internal/callback"
+	"github.com/infotecho/ocomms/internal/callers"
+	"github.com/infotecho/ocomms/internal/conference"
 	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/history"
+	"github.com/infotecho/ocomms/internal/i18n"
 	"github.com/infotecho/ocomms/internal/mail"
+	"github.com/infotecho/ocomms/internal/metrics"
+	"github.com/infotecho/ocomms/internal/phone"
+	"github.com/infotecho/ocomms/internal/presence"
+	"github.com/infotecho/ocomms/internal/profiles"
+	"github.com/infotecho/ocomms/internal/screening"
 	"github.com/infotecho/ocomms/internal/twigen"
+	"github.com/infotecho/ocomms/internal/voicemail"
 )
 
 const (
 	callStatusCompleted = "completed"
+	keyAcceptCallback   = "1"
+	keyAgentMenu        = "*"
+	keyCallBack         = "4"
+	keyChangeLanguage   = "*"
+	keyColdTransfer     = "1"
+	keyConfirmNumber    = "1"
+	keyDeleteVoicemail  = "3"
+	keyPassScreening    = "5"
 	keyRecordVoicemail  = "9"
+	keyReplayVoicemail  = "1"
+	keyScheduleCallback = "1"
+	keyVoicemailHandled = "2"
+	keyVoicemails       = "0"
+	keyWarmTransfer     = "2"
 )
 
 // VoiceHandler implements handlers for Twilio Programmable Voice hooks.
 type VoiceHandler struct {
+	Audit          *audit.Trail
+	Callers        *callers.Directory
+	Conferences    *conference.Bridge
 	Config         config.Config
 	Emailer        *mail.SendGridMailer
 	HandlerFactory *TwimlHandlerFactory
+	History        *history.Log
+	I18n           *i18n.MessageProvider
 	Logger         *slog.Logger
+	Metrics        *metrics.Metrics
+	Presence       *presence.Tracker
+	Screener       *screening.Screener
 	Twigen         *twigen.Voice
+	Voicemails     *voicemail.Box
+	Callbacks      *callback.Schedule
 }
 
-func (h VoiceHandler) inbound(actionDialOut string, actionConnectAgent string) http.HandlerFunc {
+// ringActions are the hooks involved in ringing agents for an inbound call.
+type ringActions struct {
+	acceptCall     string
+	agentStatus    string // end of an agent's call made by REST API, in conference mode
+	endCall        string
+	endConference  string
+	startVoicemail string
+}
+
+// speechOption maps spoken keywords to the equivalent key press in a menu.
+type speechOption struct {
+	key      string
+	lang     string
+	keywords func(m i18n.Messages) string
+}
+
+func (h VoiceHandler) inbound(
+	actionDialOut string,
+	actionConnectAgent string,
+	actionScreen string,
+	actionVerifyPIN string,
+) http.HandlerFunc {
 	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
-		if slices.Contains(h.Config.Twilio.AgentDIDs, params["From"]) {
-			return h.Twigen.GatherOutboundNumber(ctx, actionDialOut)
+		from := params["From"]
+
+		if _, ok := agents.ByDID(h.Config.Agents, from); ok {
+			if h.Config.Twilio.Outbound.PIN != "" {
+				return h.Twigen.GatherAgentPIN(ctx, actionVerifyPIN, h.agentLang(from))
+			}
+			return h.Twigen.GatherOutboundNumber(ctx, actionDialOut, h.agentLang(from), false)
 		}
 
-		return h.Twigen.GatherLanguage(ctx, actionConnectAgent, true)
+		result := h.Screener.Screen(from, params["StirVerstat"])
+		h.Audit.Record(ctx, params["CallSid"], audit.KindScreening, result.Action, details("reason", result.Reason))
+		call := history.Call{CallSid: params["CallSid"], From: from, To: params["To"], Start: time.Now()}
+		if result.Action == screening.ActionBlock {
+			call.Outcome = history.OutcomeBlocked
+		}
+		err := h.History.AddCall(ctx, call)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error recording call history", "err", err)
+		}
+
+		switch result.Action {
+		case screening.ActionBlock:
+			h.Screener.RecordBlocked(ctx, from, result.Reason)
+			return h.Twigen.Reject(ctx)
+		case screening.ActionChallenge:
+			h.Logger.InfoContext(ctx, "Challenging caller", "reason", result.Reason)
+			return h.Twigen.GatherScreenChallenge(ctx, actionScreen, profiles.Select(h.Config, params["To"]), keyPassScreening)
+		}
+
+		return h.greet(ctx, params, actionConnectAgent)
 	})
 }
 
-// dialOut dials out from the company to a gathered phone number.
-func (h VoiceHandler) dialOut() http.HandlerFunc {
+// screen handles a caller's response to the screening challenge.
+func (h VoiceHandler) screen(actionConnectAgent string) http.HandlerFunc {
 	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
-		digits := params["Digits"]
+		from := params["From"]
+
+		if params["Digits"] != keyPassScreening {
+			h.Audit.Record(ctx, params["CallSid"], audit.KindScreening, "challenge-failed", nil)
+			h.Screener.RecordBlocked(ctx, from, "challenge-failed")
+			h.updateCall(ctx, params["CallSid"], func(call *history.Call) { call.Outcome = history.OutcomeBlocked })
+			return h.Twigen.Hangup(ctx)
+		}
+		h.Audit.Record(ctx, params["CallSid"], audit.KindScreening, "challenge-passed", nil)
+
+		return h.greet(ctx, params, actionConnectAgent)
+	})
+}
+
+// greet welcomes a caller who passed screening.
+// The profile of the company DID they called determines the languages offered.
+func (h VoiceHandler) greet(ctx context.Context, params map[string]string, actionConnectAgent string) string {
+	profile := profiles.Select(h.Config, params["To"])
+
+	if len(profile.Languages) == 1 {
+		return h.Twigen.Welcome(ctx, actionConnectAgent, profile, profile.Languages[0])
+	}
+
+	lang, ok := h.rememberedLang(ctx, params["From"])
+	if ok && slices.Contains(profile.Languages, lang) {
+		return h.Twigen.GreetReturningCaller(ctx, actionConnectAgent, keyChangeLanguage, lang)
+	}
+
+	return h.Twigen.GatherLanguage(ctx, actionConnectAgent, profile, true)
+}
+
+// verifyPIN checks the PIN entered by an agent before letting them dial out,
+// since caller ID alone can be spoofed. Numbers entering too many incorrect PINs in a row are locked out for a while,
+// so PINs can't be guessed.
+func (h VoiceHandler) verifyPIN(actionDialOut string) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
+		pin := []byte(h.Config.Twilio.Outbound.PIN)
+		agent, _ := agents.ByDID(h.Config.Agents, params["From"])
+		lang := h.agentLang(params["From"])
+
+		failures, err := h.Callers.PINFailures(ctx, params["From"])
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error getting PIN failures", "err", err)
+			return h.Twigen.Apologize(ctx, "", lang)
+		}
+		if time.Now().Before(failures.LockedUntil) {
+			h.Logger.WarnContext(ctx, "Agent is locked out after incorrect PINs", "agent", agent.ID)
+			return h.Twigen.RejectPIN(ctx, lang, true)
+		}
+
+		if subtle.ConstantTimeCompare([]byte(params["Digits"]), pin) != 1 {
+			outbound := h.Config.Twilio.Outbound
+			failures, err = h.Callers.AddPINFailure(ctx, params["From"], outbound.PINAttempts, outbound.PINLockout)
+			if err != nil {
+				h.Logger.ErrorContext(ctx, "Error counting PIN failure", "err", err)
+			}
+			h.Logger.WarnContext(ctx, "Agent entered an incorrect PIN", "agent", agent.ID, "failures", failures.Count)
+			return h.Twigen.RejectPIN(ctx, lang, time.Now().Before(failures.LockedUntil))
+		}
+
+		if failures.Count > 0 {
+			err = h.Callers.ResetPINFailures(ctx, params["From"])
+			if err != nil {
+				h.Logger.ErrorContext(ctx, "Error resetting PIN failures", "err", err)
+			}
+		}
+
+		return h.Twigen.GatherOutboundNumber(ctx, actionDialOut, lang, false)
+	})
+}
+
+// dialOut dials out from the company to a gathered phone number,
+// or opens the agent menu or plays voicemails if the agent pressed the key for either instead.
+func (h VoiceHandler) dialOut(actionDialOut string, actionSetPresence string, actionVoicemails string) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
+		if params["Digits"] == keyVoicemails {
+			lang := h.agentLang(params["From"])
+			unheard, err := h.Voicemails.Unheard(ctx)
+			if err != nil {
+				h.Logger.ErrorContext(ctx, "Error getting voicemails", "err", err)
+			}
+			if len(unheard) == 0 {
+				return h.Twigen.SayNoVoicemails(ctx, lang, true)
+			}
+			return h.Twigen.PlayVoicemail(ctx, actionVoicemails, lang, unheard[0], len(unheard))
+		}
+
+		if params["Digits"] == keyAgentMenu {
+			agent, _ := agents.ByDID(h.Config.Agents, params["From"])
+			state, err := h.Presence.State(ctx, agent.ID)
+			if err != nil {
+				h.Logger.ErrorContext(ctx, "Error getting agent presence", "err", err)
+			}
+			return h.Twigen.GatherPresence(ctx, actionSetPresence, h.agentLang(params["From"]), state.Status)
+		}
+
+		number, err := outboundNumber(h.Config, params["Digits"])
+		if err != nil {
+			h.Logger.WarnContext(ctx, "Rejected outbound number", "err", err)
+			return h.Twigen.GatherOutboundNumber(ctx, actionDialOut, h.agentLang(params["From"]), true)
+		}
+
+		return h.Twigen.DialOut(ctx, number)
+	})
+}
+
+// bridgeCallback connects an agent who answered a call made to call back a client, to the client,
+// once the agent accepts the call back by pressing a key.
+// The call to the agent was made from the company DID, which is the caller ID shown to the client.
+func (h VoiceHandler) bridgeCallback(actionBridgeCallback string) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
+		number := params["number"]
+		lang := h.agentLang(params["To"])
+
+		if params["Digits"] != keyAcceptCallback {
+			action := twigen.WithQuery(actionBridgeCallback, url.Values{"number": []string{number}})
+			return h.Twigen.GatherBridgeCallback(ctx, action, keyAcceptCallback, number, lang)
+		}
+		return h.Twigen.BridgeCallback(ctx, number, params["From"], lang)
+	})
+}
+
+// setPresence changes an agent's availability to take calls according to their selection in the agent menu.
+func (h VoiceHandler) setPresence(actionSetPresence string) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
+		agent, ok := agents.ByDID(h.Config.Agents, params["From"])
+		if !ok {
+			h.Logger.ErrorContext(ctx, "Presence change requested from unknown agent DID", "from", params["From"])
+			return h.Twigen.Hangup(ctx)
+		}
 
-		return h.Twigen.DialOut(ctx, digits)
+		var status presence.Status
+		switch params["Digits"] {
+		case "1":
+			status = presence.StatusAvailable
+		case "2":
+			status = presence.StatusAway
+		case "3":
+			status = presence.StatusDND
+		default:
+			state, err := h.Presence.State(ctx, agent.ID)
+			if err != nil {
+				h.Logger.ErrorContext(ctx, "Error getting agent presence", "err", err)
+			}
+			return h.Twigen.GatherPresence(ctx, actionSetPresence, h.agentLang(params["From"]), state.Status)
+		}
+
+		err := h.Presence.SetState(ctx, agent.ID, presence.State{Status: status, Until: time.Time{}})
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error setting agent presence", "err", err)
+		}
+
+		return h.Twigen.SayPresenceUpdated(ctx, h.agentLang(params["From"]), status)
+	})
+}
+
+// voicemails handles an agent's selection in the voicemail menu,
+// for the voicemail identified by the voicemail query parameter.
+func (h VoiceHandler) voicemails(actionVoicemails string) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
+		if _, ok := agents.ByDID(h.Config.Agents, params["From"]); !ok {
+			h.Logger.ErrorContext(ctx, "Voicemails requested from unknown agent DID", "from", params["From"])
+			return h.Twigen.Hangup(ctx)
+		}
+		lang := h.agentLang(params["From"])
+
+		current, ok, err := h.Voicemails.Get(ctx, params["voicemail"])
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error getting voicemail", "err", err)
+			return h.Twigen.Hangup(ctx)
+		}
+
+		var prompts []func(m i18n.Messages) string
+		if !ok {
+			h.Logger.WarnContext(ctx, "Voicemail not found", "voicemail", params["voicemail"])
+			current.Time = time.Now() // start over from the newest voicemail
+			if params["voicemail"] != "" {
+				prompts = append(prompts, func(m i18n.Messages) string { return m.Voice.Voicemails.Gone })
+			}
+		}
+
+		switch digits := params["Digits"]; {
+		case !ok:
+		case digits == keyReplayVoicemail:
+			return h.Twigen.PlayVoicemail(ctx, actionVoicemails, lang, current, 0)
+		case digits == keyVoicemailHandled:
+			err = h.Voicemails.MarkHandled(ctx, current.RecordingSid)
+			prompts = append(prompts, func(m i18n.Messages) string { return m.Voice.Voicemails.Handled })
+		case digits == keyDeleteVoicemail:
+			err = h.Voicemails.Delete(ctx, current.RecordingSid)
+			prompts = append(prompts, func(m i18n.Messages) string { return m.Voice.Voicemails.Deleted })
+		case digits == keyCallBack:
+			number, dialErr := outboundNumber(h.Config, current.From)
+			if dialErr == nil {
+				return h.Twigen.DialOut(ctx, number)
+			}
+			h.Logger.WarnContext(ctx, "Rejected voicemail call back number", "err", dialErr)
+			return h.Twigen.PlayVoicemail(ctx, actionVoicemails, lang, current, 0,
+				func(m i18n.Messages) string { return m.Voice.OutboundRejected },
+			)
+		}
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error updating voicemail", "err", err)
+			prompts = nil
+		}
+
+		next, ok, err := h.Voicemails.Next(ctx, current)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error getting voicemails", "err", err)
+		}
+		if !ok {
+			return h.Twigen.SayNoVoicemails(ctx, lang, false, prompts...)
+		}
+		return h.Twigen.PlayVoicemail(ctx, actionVoicemails, lang, next, 0, prompts...)
 	})
 }
 
 // connectAgent connects an incoming caller to an agent.
 func (h VoiceHandler) connectAgent(
 	actionConnectAgent string,
-	actionAcceptCall string,
-	actionEndCall string,
+	actionRoute string,
+	actions ringActions,
 ) http.HandlerFunc {
-	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
-		callerID := params["To"]
+	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
+		profile := profiles.Select(h.Config, params["To"])
+
 		digits := params["Digits"]
+		chosenBy := "keypad"
+		if digits == "" {
+			chosenBy = "speech"
+			langKeywords := func(m i18n.Messages) string { return m.Voice.Speech.Keywords.Lang }
+			langChangeKeywords := func(m i18n.Messages) string { return m.Voice.Speech.Keywords.LangChange }
+			var options []speechOption
+			for i, lang := range profile.Languages {
+				options = append(options,
+					speechOption{key: strconv.Itoa(i + 1), lang: lang, keywords: langKeywords},
+					speechOption{key: keyChangeLanguage, lang: lang, keywords: langChangeKeywords},
+				)
+			}
+			digits = h.spokenKey(ctx, params, options)
+		}
 
-		switch digits {
-		case "1":
-			return h.Twigen.DialAgent(ctx, actionAcceptCall, actionEndCall, callerID, "en")
-		case "2":
-			return h.Twigen.DialAgent(ctx, actionAcceptCall, actionEndCall, callerID, "fr")
+		// languages are offered in the order of the profile, starting at 1
+		switch i, err := strconv.Atoi(digits); {
+		case err == nil && i >= 1 && i <= len(profile.Languages):
+			lang = profile.Languages[i-1]
+		case digits == keyChangeLanguage:
+			return h.Twigen.GatherLanguage(ctx, actionConnectAgent, profile, false)
 		default:
-			return h.Twigen.GatherLanguage(ctx, actionConnectAgent, false)
+			chosenBy = "remembered"
+			if len(profile.Languages) == 1 && digits == "" {
+				lang = profile.Languages[0] // there's no language menu to choose from
+				chosenBy = "only-language"
+			}
+			// returning callers are redirected here with their remembered lang when no key is pressed
+			if !slices.Contains(profile.Languages, lang) || digits != "" {
+				return h.Twigen.GatherLanguage(ctx, actionConnectAgent, profile, false)
+			}
+		}
+
+		if chosenBy == "keypad" || chosenBy == "speech" {
+			h.Metrics.LanguageChosen(lang)
+		}
+		h.Audit.Record(ctx, params["CallSid"], audit.KindLanguage, lang, details("chosenBy", chosenBy))
+		h.rememberLang(ctx, params["From"], lang)
+		h.updateCall(ctx, params["CallSid"], func(call *history.Call) { call.Lang = lang })
+
+		open, err := profiles.Open(profile, h.I18n.Local(time.Now()))
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error checking business hours", "err", err, "profile", profile.ID)
+		}
+		hours := "open"
+		if !open {
+			hours = "closed"
+		}
+		h.Audit.Record(ctx, params["CallSid"], audit.KindBusinessHours, hours, details("profile", profile.ID))
+		if !open {
+			h.Logger.InfoContext(ctx, "Outside business hours, going to voicemail", "profile", profile.ID)
+			return h.Twigen.GatherVoicemailClosed(ctx, actions.startVoicemail, keyRecordVoicemail, keyScheduleCallback, lang)
 		}
+
+		if len(profile.Menu) > 0 {
+			return h.Twigen.GatherMenu(ctx, actionRoute, lang, profile.Menu)
+		}
+		return h.ringAgents(ctx, actions, params, lang, "", 0)
 	})
 }
 
+// route connects a caller to the skill group of agents they selected in the routing menu.
+func (h VoiceHandler) route(actionRoute string, actions ringActions) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
+		digits := params["Digits"]
+		if digits == "" {
+			// no selection, ring agents regardless of skill
+			return h.ringAgents(ctx, actions, params, lang, "", 0)
+		}
+
+		profile := profiles.Select(h.Config, params["To"])
+		for _, option := range profile.Menu {
+			if option.Digit == digits {
+				return h.ringAgents(ctx, actions, params, lang, option.Skill, 0)
+			}
+		}
+		return h.Twigen.GatherMenu(ctx, actionRoute, lang, profile.Menu)
+	})
+}
+
+// ringAgents dials the available agents in the given stage of ring groups for the caller's language and skill,
+// or invites the caller to leave a voicemail if there are no agents left to ring.
+func (h VoiceHandler) ringAgents(
+	ctx context.Context,
+	actions ringActions,
+	params map[string]string,
+	lang string,
+	skill string,
+	stage int,
+) string {
+	profile := profiles.Select(h.Config, params["To"])
+	available, err := h.Presence.Available(ctx, profiles.Agents(profile, h.Config.Agents))
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error getting agent presence", "err", err)
+	}
+
+	groups := agents.RingGroups(available, lang, skill)
+	if stage >= len(groups) {
+		h.Logger.InfoContext(ctx, "No agents available, going to voicemail", "skill", skill, "stage", stage)
+		h.Audit.Record(ctx, params["CallSid"], audit.KindRing, "no-agents",
+			details("skill", skill, "stage", strconv.Itoa(stage)),
+		)
+		return h.Twigen.GatherVoicemailStart(ctx, actions.startVoicemail, keyRecordVoicemail, keyScheduleCallback, lang)
+	}
+
+	// the ring stage is carried to endCall to fall back to the next group if nobody answers
+	query := url.Values{}
+	if skill != "" {
+		query.Set("skill", skill)
+	}
+	if stage > 0 {
+		query.Set("stage", strconv.Itoa(stage))
+	}
+
+	agentIDs := make([]string, len(groups[stage]))
+	for i, agent := range groups[stage] {
+		agentIDs[i] = agent.ID
+	}
+	h.Audit.Record(ctx, params["CallSid"], audit.KindRing, "ring-group",
+		details("skill", skill, "stage", strconv.Itoa(stage), "agents", strings.Join(agentIDs, ",")),
+	)
+
+	agentDIDs := agents.PhoneNumbers(groups[stage])
+	if h.Config.Twilio.Conference.Enabled {
+		twiml, err := h.ringConference(ctx, actions, params, lang, query, agentDIDs)
+		if err == nil {
+			return twiml
+		}
+		h.Logger.ErrorContext(ctx, "Error ringing agents into conference, dialing directly", "err", err)
+	}
+
+	return h.Twigen.DialAgent(ctx, actions.acceptCall, twigen.WithQuery(actions.endCall, query), params["To"], lang, agentDIDs)
+}
+
+// ringConference puts the caller in a conference and rings agents through the REST API to join it.
+// If none of the agents join, the caller is redirected to endCall to ring the next group.
+func (h VoiceHandler) ringConference(
+	ctx context.Context,
+	actions ringActions,
+	params map[string]string,
+	lang string,
+	query url.Values,
+	agentDIDs []string,
+) (string, error) {
+	room := conference.Room(params["CallSid"])
+
+	fallback := url.Values{}
+	maps.Copy(fallback, query)
+	// Twilio doesn't post a DialCallStatus when redirecting the caller out of the conference
+	fallback.Set("DialCallStatus", "no-answer")
+	fallback.Set("lang", lang)
+	err := h.Conferences.Open(ctx, room, params["CallSid"], twigen.WithQuery(actions.endCall, fallback))
+	if err != nil {
+		return "", fmt.Errorf("failed to open conference: %w", err)
+	}
+
+	err = h.ringIntoConference(ctx, actions, room, params["To"], lang, agentDIDs, false)
+	if err != nil {
+		return "", err
+	}
+
+	return h.Twigen.WaitInConference(ctx, twigen.WithQuery(actions.endConference, conferenceQuery(room)), room, lang), nil
+}
+
+// ringIntoConference rings agents through the REST API to join room once they accept the call.
+func (h VoiceHandler) ringIntoConference(
+	ctx context.Context,
+	actions ringActions,
+	room string,
+	callerID string,
+	lang string,
+	agentDIDs []string,
+	transfer bool,
+) error {
+	acceptQuery := conferenceQuery(room)
+	acceptQuery.Set("lang", lang)
+
+	err := h.Conferences.Ring(ctx, room, callerID, agentDIDs,
+		twigen.WithQuery(actions.acceptCall, acceptQuery),
+		twigen.WithQuery(actions.agentStatus, conferenceQuery(room)),
+		transfer,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to ring agents into conference: %w", err)
+	}
+	return nil
+}
+
+func conferenceQuery(room string) url.Values {
+	return url.Values{"conference": []string{room}}
+}
+
+// spokenKey returns the key equivalent to the menu option spoken by the caller,
+// or an empty string if no option was recognized with sufficient confidence.
+func (h VoiceHandler) spokenKey(ctx context.Context, params map[string]string, options []speechOption) string {
+	speech := params["SpeechResult"]
+	if speech == "" {
+		return ""
+	}
+
+	confidence, err := strconv.ParseFloat(params["Confidence"], 64)
+	if err != nil || confidence < h.Config.Twilio.Speech.MinConfidence {
+		h.Logger.InfoContext(ctx, "Ignoring low confidence speech result", "confidence", params["Confidence"])
+		return ""
+	}
+
+	for _, option := range options {
+		keywords := i18n.Keywords(h.I18n.Message(ctx, option.lang, option.keywords))
+		if i18n.MatchesKeyword(speech, keywords) {
+			return option.key
+		}
+	}
+
+	h.Logger.InfoContext(ctx, "Speech result did not match any menu option", "confidence", confidence)
+	return ""
+}
+
+// rememberedLang returns the language previously selected by a caller, if any.
+func (h VoiceHandler) rememberedLang(ctx context.Context, from string) (string, bool) {
+	if from == "" || callers.Anonymous(from) {
+		return "", false
+	}
+
+	lang, ok, err := h.Callers.Language(ctx, from)
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error getting remembered language of caller", "err", err)
+		return "", false
+	}
+	if _, supported := h.Config.Twilio.Languages[lang]; !ok || !supported {
+		return "", false
+	}
+
+	return lang, true
+}
+
+// rememberLang persists a caller's language selection so that it can be skipped on their next call.
+func (h VoiceHandler) rememberLang(ctx context.Context, from string, lang string) {
+	if from == "" || callers.Anonymous(from) { // shared by every caller withholding caller ID
+		return
+	}
+
+	err := h.Callers.SetLanguage(ctx, from, lang)
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error remembering language of caller", "err", err)
+	}
+}
+
 // acceptCall prompts an agent to press a key to accept the call,
 // to distinguish from their personal voicemail answering the call.
 func (h VoiceHandler) acceptCall(actionConfirmConnected string) http.HandlerFunc {
-	return h.HandlerFactory.handler(func(ctx context.Context, lang string, _ map[string]string) string {
+	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
+		if room := params["conference"]; room != "" {
+			return h.Twigen.GatherAccept(ctx, twigen.WithQuery(actionConfirmConnected, conferenceQuery(room)), lang)
+		}
 		return h.Twigen.GatherAccept(ctx, actionConfirmConnected, lang)
 	})
 }
 
 // confirmConnected confirms to the agent that they were connected to the call after accepting it.
-func (h VoiceHandler) confirmConnected() http.HandlerFunc {
-	return h.HandlerFactory.handler(func(ctx context.Context, lang string, _ map[string]string) string {
-		return h.Twigen.SayConnected(ctx, lang)
+// In conference mode, the agent joins the conference unless another agent accepted the call first.
+func (h VoiceHandler) confirmConnected(actionTransferMenu string) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
+		room := params["conference"]
+		if room == "" {
+			h.Metrics.CallOutcome(metrics.OutcomeAnswered)
+			h.answered(ctx, params["ParentCallSid"], params["To"])
+			return h.Twigen.SayConnected(ctx, lang)
+		}
+
+		joined, first, err := h.Conferences.Join(ctx, room, params["CallSid"])
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error joining agent to conference", "err", err)
+		}
+		if !joined {
+			return h.Twigen.Hangup(ctx)
+		}
+		if first {
+			h.Metrics.CallOutcome(metrics.OutcomeAnswered)
+		}
+		h.answered(ctx, conference.CallerSid(room), params["To"])
+
+		return h.Twigen.JoinConference(ctx, twigen.WithQuery(actionTransferMenu, conferenceQuery(room)), room, lang,
+			func(m i18n.Messages) string { return m.Voice.ConfirmConnected },
+			func(m i18n.Messages) string { return m.Voice.Transfer.MenuHint },
+		)
+	})
+}
+
+// transferMenu offers transfer options to an agent who pressed star during a conference.
+func (h VoiceHandler) transferMenu(actionTransfer string) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
+		room := params["conference"]
+		// the agent also lands here when the caller hangs up and ends the conference
+		active, err := h.Conferences.Active(ctx, room)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error getting conference", "err", err)
+		}
+		if !active {
+			return h.Twigen.Hangup(ctx)
+		}
+
+		return h.Twigen.GatherTransfer(ctx, twigen.WithQuery(actionTransfer, conferenceQuery(room)), lang)
+	})
+}
+
+// transfer rings other available agents into the conference, either for the agent to leave the caller to them
+// (cold transfer) or to introduce the caller (warm transfer), before returning the agent to the call.
+func (h VoiceHandler) transfer(actionTransferMenu string, actions ringActions) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
+		room := params["conference"]
+		actionMenu := twigen.WithQuery(actionTransferMenu, conferenceQuery(room))
+		digits := params["Digits"]
+		if digits != keyColdTransfer && digits != keyWarmTransfer {
+			return h.Twigen.JoinConference(ctx, actionMenu, room, lang)
+		}
+
+		// calls to agents made by REST API are from the company DID to the agent's DID
+		agent, _ := agents.ByDID(h.Config.Agents, params["To"])
+		others := slices.DeleteFunc(slices.Clone(h.Config.Agents), func(a config.Agent) bool { return a.ID == agent.ID })
+		available, err := h.Presence.Available(ctx, others)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error getting agent presence", "err", err)
+		}
+		groups := agents.RingGroups(available, lang, "")
+		if len(groups) == 0 {
+			h.Logger.InfoContext(ctx, "No other agents available for transfer")
+			return h.Twigen.JoinConference(ctx, actionMenu, room, lang,
+				func(m i18n.Messages) string { return m.Voice.Transfer.Unavailable },
+			)
+		}
+
+		cold := digits == keyColdTransfer
+		err = h.ringIntoConference(ctx, actions, room, params["From"], lang, agents.PhoneNumbers(groups[0]), cold)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error transferring call", "err", err)
+			return h.Twigen.JoinConference(ctx, actionMenu, room, lang,
+				func(m i18n.Messages) string { return m.Voice.Transfer.Unavailable },
+			)
+		}
+
+		if cold {
+			return h.Twigen.SayTransferred(ctx, lang)
+		}
+		return h.Twigen.JoinConference(ctx, actionMenu, room, lang,
+			func(m i18n.Messages) string { return m.Voice.Transfer.Adding },
+		)
+	})
+}
+
+// agentStatus handles the end of a call to an agent made by REST API in conference mode.
+func (h VoiceHandler) agentStatus() http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
+		err := h.Conferences.Leave(ctx, params["conference"], params["CallSid"])
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error removing agent from conference", "err", err)
+		}
+		return h.Twigen.Noop(ctx)
+	})
+}
+
+// endConference handles the caller leaving the conference, e.g. by hanging up while agents are rung.
+func (h VoiceHandler) endConference() http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, _ string, params map[string]string) string {
+		err := h.Conferences.Close(ctx, params["conference"])
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error closing conference", "err", err)
+		}
+		return h.Twigen.Noop(ctx)
 	})
 }
 
 // endCall handles the end of an inbound call, whether successful (agent picks up)
 // or unsuccessful (busy tone or call goes to agent voicemail).
-func (h VoiceHandler) endCall(actionStartRecording string) http.HandlerFunc {
+func (h VoiceHandler) endCall(actions ringActions) http.HandlerFunc {
 	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
 		callStatus := params["DialCallStatus"]
 		callDuration := params["DialCallDuration"]
+		status := details("dialCallStatus", callStatus, "dialCallDuration", callDuration, "stage", params["stage"])
 
 		switch {
 		case callStatus == "busy",
 			callStatus == "no-answer",
 			// indicates call went to agent's voicemail - no key pressed to accept call
 			callStatus == callStatusCompleted && callDuration == "":
-			return h.Twigen.GatherVoicemailStart(ctx, actionStartRecording, keyRecordVoicemail, lang)
+			if callStatus == "busy" {
+				h.Metrics.CallOutcome(metrics.OutcomeBusy)
+			} else {
+				h.Metrics.CallOutcome(metrics.OutcomeNoAnswer)
+			}
+			h.Audit.Record(ctx, params["CallSid"], audit.KindDialStatus, "next-group", status)
+			stage, _ := strconv.Atoi(params["stage"]) // first stage when absent
+			return h.ringAgents(ctx, actions, params, lang, params["skill"], stage+1)
 		case callStatus == callStatusCompleted:
+			h.Audit.Record(ctx, params["CallSid"], audit.KindDialStatus, "answered", status)
+			return h.Twigen.Noop(ctx)
+		case callStatus == "canceled": // the caller hung up before an agent answered
+			h.Metrics.CallOutcome(metrics.OutcomeAbandoned)
+			h.Audit.Record(ctx, params["CallSid"], audit.KindDialStatus, "abandoned", status)
+			h.updateCall(ctx, params["CallSid"], func(call *history.Call) {
+				call.Outcome = history.OutcomeAbandoned
+				call.Wait = time.Since(call.Start)
+			})
 			return h.Twigen.Noop(ctx)
 		default:
 			h.Logger.ErrorContext(ctx, "Unexpected DialCallStatus: "+callStatus)
+			h.Audit.Record(ctx, params["CallSid"], audit.KindDialStatus, "unexpected", status)
 			return h.Twigen.Noop(ctx)
 		}
 	})
 }
 
-// startVoicemail handles a key press after a caller was invited to press 9 to leave a message.
+// startVoicemail handles a key press after a caller was invited to press 9 to leave a message,
+// or to press 1 to schedule a call back.
 func (h VoiceHandler) startVoicemail(
 	actionStartVoicemail string,
 	actionEndVoicemail string,
+	actionCallbackNumber string,
 ) http.HandlerFunc {
 	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
 		digits := params["Digits"]
+		if digits == "" {
+			digits = h.spokenKey(ctx, params, []speechOption{{
+				key:      keyRecordVoicemail,
+				lang:     lang,
+				keywords: func(m i18n.Messages) string { return m.Voice.Speech.Keywords.Voicemail },
+			}})
+		}
+
+		if digits == keyScheduleCallback && h.Config.Callbacks.Scheduled.Enabled {
+			number, err := outboundNumber(h.Config, params["From"])
+			if err != nil {
+				number = "" // e.g. anonymous callers must enter the number to call back
+			}
+			return h.Twigen.GatherCallbackNumber(ctx, actionCallbackNumber, lang, number, false)
+		}
 
 		if digits != keyRecordVoicemail {
-			return h.Twigen.GatherVoicemailStart(ctx, actionStartVoicemail, keyRecordVoicemail, lang)
+			return h.Twigen.GatherVoicemailStart(ctx, actionStartVoicemail, keyRecordVoicemail, keyScheduleCallback, lang)
 		}
 
 		return h.Twigen.RecordVoicemail(
@@ -129,20 +809,181 @@ func (h VoiceHandler) startVoicemail(
 func (h VoiceHandler) endVoicemail(actionEndVoicemail string) http.HandlerFunc {
 	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
 		digits := params["Digits"]
+		rerecords, _ := strconv.Atoi(params["rerecords"]) // none when absent
 
 		if digits == "hangup" {
 			from := params["From"]
 			recordingSID := params["RecordingSid"]
-			h.Emailer.Voicemail(ctx, lang, from, recordingSID)
+			err := h.Voicemails.Add(ctx, voicemail.Voicemail{
+				RecordingSid: recordingSID,
+				RecordingURL: params["RecordingUrl"],
+				From:         from,
+				To:           params["To"],
+				Time:         time.Now(),
+				Handled:      false,
+			})
+			if err != nil {
+				h.Logger.ErrorContext(ctx, "Error saving voicemail", "err", err)
+			}
+			h.Metrics.CallOutcome(metrics.OutcomeVoicemail)
+			h.Audit.Record(ctx, params["CallSid"], audit.KindVoicemail, "left", details("rerecords", strconv.Itoa(rerecords)))
+			h.updateCall(ctx, params["CallSid"], func(call *history.Call) {
+				call.Outcome = history.OutcomeVoicemail
+				call.RecordingSid = recordingSID
+			})
+			h.Emailer.Voicemail(ctx, lang, profiles.Select(h.Config, params["To"]), from, params["To"], recordingSID)
 			return h.Twigen.Noop(ctx)
 		}
 
+		rerecords++
+		h.Audit.Record(ctx, params["CallSid"], audit.KindVoicemail, "rerecord", details("rerecords", strconv.Itoa(rerecords)))
 		return h.Twigen.RecordVoicemail(
 			ctx,
-			actionEndVoicemail,
+			twigen.WithQuery(actionEndVoicemail, url.Values{"rerecords": []string{strconv.Itoa(rerecords)}}),
 			keyRecordVoicemail,
 			lang,
 			true,
 		)
 	})
 }
+
+// callbackNumber handles the number a caller confirmed or entered to be called back at,
+// and offers them the next available slots.
+func (h VoiceHandler) callbackNumber(
+	actionCallbackNumber string,
+	actionCallbackSlot string,
+	actionStartVoicemail string,
+) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
+		number, err := outboundNumber(h.Config, params["Digits"])
+		if caller, callerErr := outboundNumber(h.Config, params["From"]); callerErr == nil && params["Digits"] == keyConfirmNumber {
+			number, err = caller, nil
+		}
+		if err != nil {
+			h.Logger.WarnContext(ctx, "Rejected call back number", "err", err)
+			return h.Twigen.GatherCallbackNumber(ctx, actionCallbackNumber, lang, "", true)
+		}
+
+		slots := h.callbackSlots(ctx, profiles.Select(h.Config, params["To"]))
+		if len(slots) == 0 {
+			h.Logger.InfoContext(ctx, "No call back slots available, going to voicemail")
+			return h.Twigen.GatherVoicemailNoSlots(ctx, actionStartVoicemail, keyRecordVoicemail, lang)
+		}
+
+		return h.Twigen.GatherCallbackSlot(ctx, actionCallbackSlot, lang, number, slots)
+	})
+}
+
+// callbackSlot schedules a call back in the slot a caller chose, and notifies agents by email.
+func (h VoiceHandler) callbackSlot(actionCallbackSlot string, actionStartVoicemail string) http.HandlerFunc {
+	return h.HandlerFactory.handler(func(ctx context.Context, lang string, params map[string]string) string {
+		number := params["number"]
+		var slots []time.Time
+		for _, unix := range strings.Split(params["slots"], ",") {
+			seconds, err := strconv.ParseInt(unix, 10, 64)
+			if err != nil {
+				h.Logger.ErrorContext(ctx, "Invalid call back slot", "err", err)
+				return h.Twigen.GatherVoicemailNoSlots(ctx, actionStartVoicemail, keyRecordVoicemail, lang)
+			}
+			slots = append(slots, time.Unix(seconds, 0))
+		}
+
+		choice, err := strconv.Atoi(params["Digits"])
+		if err != nil || choice < 1 || choice > len(slots) {
+			return h.Twigen.GatherCallbackSlot(ctx, actionCallbackSlot, lang, number, slots)
+		}
+		slot := slots[choice-1]
+
+		err = h.Callbacks.Add(ctx, callback.Request{
+			CallSid: params["CallSid"],
+			Number:  number,
+			To:      params["To"],
+			Lang:    lang,
+			Time:    slot,
+		})
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "Error scheduling call back", "err", err)
+			return h.Twigen.GatherVoicemailNoSlots(ctx, actionStartVoicemail, keyRecordVoicemail, lang)
+		}
+
+		h.Logger.InfoContext(ctx, "Scheduled call back", "time", slot)
+		h.Metrics.CallOutcome(metrics.OutcomeCallbackScheduled)
+		h.updateCall(ctx, params["CallSid"], func(call *history.Call) { call.Outcome = history.OutcomeCallbackScheduled })
+		h.Emailer.ScheduledCallback(ctx, lang, profiles.Select(h.Config, params["To"]), number, params["To"], slot)
+		return h.Twigen.SayCallbackScheduled(ctx, lang, slot)
+	})
+}
+
+// agentLang returns the language to prompt the agent calling from did in: their first language, or the default one.
+func (h VoiceHandler) agentLang(did string) string {
+	agent, ok := agents.ByDID(h.Config.Agents, did)
+	if ok && len(agent.Languages) > 0 {
+		return agent.Languages[0]
+	}
+	return h.Config.I18N.DefaultLang
+}
+
+// answered records in the call history that an agent, rung at agentDID, answered the caller's call.
+// Agents taking a transferred call don't change who answered it.
+func (h VoiceHandler) answered(ctx context.Context, callerSid string, agentDID string) {
+	agent, _ := agents.ByDID(h.Config.Agents, agentDID)
+	h.updateCall(ctx, callerSid, func(call *history.Call) {
+		if call.Outcome != "" {
+			return
+		}
+		call.Outcome = history.OutcomeAnswered
+		call.Agent = agent.ID
+		call.Wait = time.Since(call.Start)
+	})
+}
+
+// updateCall updates a caller's call in the call history.
+func (h VoiceHandler) updateCall(ctx context.Context, callSid string, fn func(call *history.Call)) {
+	err := h.History.UpdateCall(ctx, callSid, fn)
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error updating call history", "err", err)
+	}
+}
+
+// callbackSlots returns the slots to offer callers of a profile scheduling a call back.
+// Each of the profile's agents can take one call back per slot.
+func (h VoiceHandler) callbackSlots(ctx context.Context, profile config.Profile) []time.Time {
+	pending, err := h.Callbacks.Pending(ctx)
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "Error getting scheduled call backs", "err", err)
+	}
+
+	var booked []time.Time
+	for _, request := range pending {
+		if profiles.Select(h.Config, request.To).ID == profile.ID {
+			booked = append(booked, request.Time)
+		}
+	}
+
+	capacity := len(profiles.Agents(profile, h.Config.Agents))
+	return callback.Slots(h.Config, profile, h.I18n.Local(time.Now()), capacity, booked)
+}
+
+// outboundNumber normalizes a number to E.164 format and checks it against the outbound dialing policy.
+func outboundNumber(conf config.Config, digits string) (string, error) {
+	number, err := phone.NormalizeE164(digits)
+	if err == nil {
+		outbound := conf.Twilio.Outbound
+		err = phone.CheckDialPolicy(number, outbound.AllowedCountryCodes, outbound.DeniedPrefixes)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot dial number: %w", err)
+	}
+	return number, nil
+}
+
+// details returns the details of an audit event from key-value pairs, leaving out empty values.
+func details(keyValues ...string) map[string]string {
+	details := map[string]string{}
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		if keyValues[i+1] != "" {
+			details[keyValues[i]] = keyValues[i+1]
+		}
+	}
+	return details
+}
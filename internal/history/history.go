@@ -0,0 +1,260 @@
+// Package history keeps a record of the calls and text messages received, for reports and exports.
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+// Outcome is how an inbound call ended.
+type Outcome string
+
+// Outcomes of inbound calls. Calls without an outcome were hung up before reaching agents or voicemail.
+const (
+	OutcomeAnswered          Outcome = "answered"
+	OutcomeVoicemail         Outcome = "voicemail"
+	OutcomeCallbackScheduled Outcome = "callback-scheduled"
+	OutcomeAbandoned         Outcome = "abandoned" // the caller hung up while agents were rung
+	OutcomeBlocked           Outcome = "blocked"   // by call screening
+)
+
+// Call is an inbound call from a client.
+type Call struct {
+	CallSid      string        `json:"callSid"`
+	From         string        `json:"from"`
+	To           string        `json:"to"` // company DID the caller called
+	Start        time.Time     `json:"start"`
+	Lang         string        `json:"lang,omitempty"`
+	Outcome      Outcome       `json:"outcome,omitempty"`
+	Agent        string        `json:"agent,omitempty"`        // ID of the agent who answered
+	Wait         time.Duration `json:"wait,omitempty"`         // from the start of the call until it was answered or abandoned
+	RecordingSid string        `json:"recordingSid,omitempty"` // of the voicemail left
+}
+
+// Message is an inbound text message from a client.
+type Message struct {
+	MessageSid string    `json:"messageSid"`
+	From       string    `json:"from"`
+	To         string    `json:"to"` // company DID texted
+	Time       time.Time `json:"time"`
+	Body       string    `json:"body"`
+}
+
+// Log persists calls and text messages in daily buckets, by UTC date.
+type Log struct {
+	Store     store.Store
+	Retention time.Duration // buckets are deleted once their day ended this long ago, or kept if 0
+
+	mu sync.Mutex // serializes updates from concurrent calls
+}
+
+const dateLayout = "2006-01-02"
+
+func callsKey(day time.Time) string {
+	return "history/calls/" + day.UTC().Format(dateLayout)
+}
+
+func callDayKey(callSid string) string {
+	return "history/call-days/" + callSid
+}
+
+func messagesKey(day time.Time) string {
+	return "history/messages/" + day.UTC().Format(dateLayout)
+}
+
+const prunedKey = "history/pruned"
+
+// AddCall records the start of an inbound call.
+func (l *Log) AddCall(ctx context.Context, call Call) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var calls []Call
+	err := l.load(ctx, callsKey(call.Start), &calls)
+	if err != nil {
+		return err
+	}
+	err = l.save(ctx, callsKey(call.Start), append(calls, call))
+	if err != nil {
+		return err
+	}
+
+	// updates find the call's bucket even if it ends the next day
+	err = l.Store.Set(ctx, callDayKey(call.CallSid), []byte(call.Start.UTC().Format(dateLayout)))
+	if err != nil {
+		return fmt.Errorf("failed to set call history day: %w", err)
+	}
+	return l.expire(ctx, call.Start)
+}
+
+// UpdateCall updates a recorded call with fn. Calls that weren't recorded are ignored.
+func (l *Log) UpdateCall(ctx context.Context, callSid string, fn func(call *Call)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	value, ok, err := l.Store.Get(ctx, callDayKey(callSid))
+	if err != nil {
+		return fmt.Errorf("failed to get call history day: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	day, err := time.Parse(dateLayout, string(value))
+	if err != nil {
+		return fmt.Errorf("failed to parse call history day: %w", err)
+	}
+
+	var calls []Call
+	err = l.load(ctx, callsKey(day), &calls)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(calls, func(c Call) bool { return c.CallSid == callSid })
+	if i < 0 {
+		return nil
+	}
+	fn(&calls[i])
+	return l.save(ctx, callsKey(day), calls)
+}
+
+// AddMessage records an inbound text message.
+func (l *Log) AddMessage(ctx context.Context, message Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var messages []Message
+	err := l.load(ctx, messagesKey(message.Time), &messages)
+	if err != nil {
+		return err
+	}
+	err = l.save(ctx, messagesKey(message.Time), append(messages, message))
+	if err != nil {
+		return err
+	}
+	return l.expire(ctx, message.Time)
+}
+
+// expire deletes the buckets of days past the retention period, after a record was added for written.
+func (l *Log) expire(ctx context.Context, written time.Time) error {
+	if l.Retention == 0 {
+		return nil
+	}
+
+	err := store.PruneDays(ctx, l.Store, prunedKey, written, time.Now().Add(-l.Retention), l.prune)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired history: %w", err)
+	}
+	return nil
+}
+
+// prune deletes the calls and text messages of day.
+func (l *Log) prune(ctx context.Context, day time.Time) error {
+	var calls []Call
+	err := l.load(ctx, callsKey(day), &calls)
+	if err != nil {
+		return err
+	}
+	keys := []string{callsKey(day), messagesKey(day)}
+	for _, call := range calls {
+		keys = append(keys, callDayKey(call.CallSid))
+	}
+
+	for _, key := range keys {
+		err = l.Store.Delete(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to delete history: %w", err)
+		}
+	}
+	return nil
+}
+
+// Calls returns the calls started from from until to, earliest first.
+func (l *Log) Calls(ctx context.Context, from time.Time, to time.Time) ([]Call, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var calls []Call
+	for day := range days(from, to) {
+		var bucket []Call
+		err := l.load(ctx, callsKey(day), &bucket)
+		if err != nil {
+			return nil, err
+		}
+		for _, call := range bucket {
+			if !call.Start.Before(from) && call.Start.Before(to) {
+				calls = append(calls, call)
+			}
+		}
+	}
+	slices.SortStableFunc(calls, func(a, b Call) int { return a.Start.Compare(b.Start) })
+	return calls, nil
+}
+
+// Messages returns the text messages received from from until to, earliest first.
+func (l *Log) Messages(ctx context.Context, from time.Time, to time.Time) ([]Message, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var messages []Message
+	for day := range days(from, to) {
+		var bucket []Message
+		err := l.load(ctx, messagesKey(day), &bucket)
+		if err != nil {
+			return nil, err
+		}
+		for _, message := range bucket {
+			if !message.Time.Before(from) && message.Time.Before(to) {
+				messages = append(messages, message)
+			}
+		}
+	}
+	slices.SortStableFunc(messages, func(a, b Message) int { return a.Time.Compare(b.Time) })
+	return messages, nil
+}
+
+// days iterates over the UTC days overlapping from until to.
+func days(from time.Time, to time.Time) func(yield func(time.Time) bool) {
+	return func(yield func(time.Time) bool) {
+		start := from.UTC().Truncate(24 * time.Hour) //nolint:mnd
+		for day := start; day.Before(to); day = day.AddDate(0, 0, 1) {
+			if !yield(day) {
+				return
+			}
+		}
+	}
+}
+
+func (l *Log) load(ctx context.Context, key string, records any) error {
+	value, ok, err := l.Store.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+
+	err = json.Unmarshal(value, records)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal history: %w", err)
+	}
+	return nil
+}
+
+func (l *Log) save(ctx context.Context, key string, records any) error {
+	value, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	err = l.Store.Set(ctx, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to set history: %w", err)
+	}
+	return nil
+}
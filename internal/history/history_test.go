@@ -0,0 +1,115 @@
+package history_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/infotecho/ocomms/internal/history"
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	log := &history.Log{Store: store.NewMemory()}
+
+	// a call just before midnight, updated the next day
+	late := time.Date(2024, 11, 4, 23, 59, 0, 0, time.UTC)
+	early := time.Date(2024, 11, 5, 8, 0, 0, 0, time.UTC)
+	for _, call := range []history.Call{
+		{CallSid: "CA1", From: "+16135550101", Start: late},
+		{CallSid: "CA2", From: "+16135550102", Start: early},
+	} {
+		if err := log.AddCall(ctx, call); err != nil {
+			t.Fatalf("AddCall() error: %v", err)
+		}
+	}
+
+	err := log.UpdateCall(ctx, "CA1", func(call *history.Call) { call.Outcome = history.OutcomeAnswered })
+	if err != nil {
+		t.Fatalf("UpdateCall() error: %v", err)
+	}
+	err = log.UpdateCall(ctx, "CA-unknown", func(*history.Call) { t.Error("Unrecorded call updated") })
+	if err != nil {
+		t.Fatalf("UpdateCall() of unrecorded call error: %v", err)
+	}
+
+	calls, err := log.Calls(ctx, late.Add(-time.Hour), early.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Calls() error: %v", err)
+	}
+	want := []history.Call{
+		{CallSid: "CA1", From: "+16135550101", Start: late, Outcome: history.OutcomeAnswered},
+		{CallSid: "CA2", From: "+16135550102", Start: early},
+	}
+	if diff := cmp.Diff(want, calls); diff != "" {
+		t.Errorf("Calls() mismatch (-want +got):\n%s", diff)
+	}
+
+	calls, err = log.Calls(ctx, early, early.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Calls() error: %v", err)
+	}
+	if len(calls) != 1 || calls[0].CallSid != "CA2" {
+		t.Errorf("Calls() from %v = %v, want CA2 only", early, calls)
+	}
+
+	message := history.Message{MessageSid: "SM1", From: "+16135550101", Time: early, Body: "Hello"}
+	if err = log.AddMessage(ctx, message); err != nil {
+		t.Fatalf("AddMessage() error: %v", err)
+	}
+	messages, err := log.Messages(ctx, late, early.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Messages() error: %v", err)
+	}
+	if diff := cmp.Diff([]history.Message{message}, messages); diff != "" {
+		t.Errorf("Messages() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLog_retention(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage, err := store.NewFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := &history.Log{Store: storage, Retention: 7 * 24 * time.Hour}
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -10)
+	if err := log.AddCall(ctx, history.Call{CallSid: "CA-old", Start: old}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.AddMessage(ctx, history.Message{MessageSid: "SM-old", Time: old, Body: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.AddCall(ctx, history.Call{CallSid: "CA-new", Start: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	calls, err := log.Calls(ctx, old.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0].CallSid != "CA-new" {
+		t.Errorf("Calls() = %+v, want CA-new only", calls)
+	}
+	messages, err := log.Messages(ctx, old.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("Messages() = %+v, want expired message bodies deleted", messages)
+	}
+
+	// updates of expired calls are ignored
+	err = log.UpdateCall(ctx, "CA-old", func(*history.Call) { t.Error("Expired call updated") })
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,27 @@
+// Package httputil provides helpers shared by HTTP middleware.
+package httputil
+
+import "net/http"
+
+// StatusRecorder keeps the status code written to a response.
+type StatusRecorder struct {
+	http.ResponseWriter
+
+	Status int
+}
+
+// NewStatusRecorder returns a StatusRecorder for w, with the status of responses whose handler doesn't write one.
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+}
+
+// WriteHeader records status before writing it.
+func (r *StatusRecorder) WriteHeader(status int) {
+	r.Status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets [http.ResponseController] reach the underlying response writer.
+func (r *StatusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
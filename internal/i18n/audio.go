@@ -0,0 +1,38 @@
+package i18n
+
+import (
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+//nolint:gochecknoglobals
+var audioRe = regexp.MustCompile(`^\[play:([^\]]+)\]\s*`)
+
+// Audio splits an i18n message starting with a reference to an audio prompt, e.g. "[play:welcome.mp3] Welcome.",
+// into the prompt's file name or URL and the text to speak if the prompt can't be played.
+// The asset is empty if the message doesn't reference a prompt.
+func Audio(msg string) (asset string, text string) {
+	match := audioRe.FindStringSubmatch(msg)
+	if match == nil {
+		return "", msg
+	}
+	return strings.TrimSpace(match[1]), msg[len(match[0]):]
+}
+
+// AudioAssets returns the audio prompts referenced by i18n messages, by language.
+func (mp MessageProvider) AudioAssets() map[string][]string {
+	assets := map[string][]string{}
+	for lang, messages := range mp.messages {
+		var langAssets []string
+		walkStrings(reflect.ValueOf(messages), func(msg string) {
+			if asset, _ := Audio(msg); asset != "" && !slices.Contains(langAssets, asset) {
+				langAssets = append(langAssets, asset)
+			}
+		})
+		slices.Sort(langAssets)
+		assets[lang] = langAssets
+	}
+	return assets
+}
@@ -5,29 +5,44 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"regexp"
+	"slices"
+	"time"
 
 	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/metrics"
+	"github.com/infotecho/ocomms/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // MessageProvider provides localized messages.
 type MessageProvider struct {
 	messages map[string]Messages
+	location *time.Location
 	logger   *slog.Logger
+	metrics  *metrics.Metrics
 	config   config.Config
 }
 
 // NewMessageProvider loads i18n messages and creates a MessageProvider instance to access them.
-// Returns error if unable to load messages.
-func NewMessageProvider(logger *slog.Logger, config config.Config) (*MessageProvider, error) {
-	messages, err := loadMessages()
+// Returns error if unable to load messages. metrics may be nil.
+func NewMessageProvider(logger *slog.Logger, config config.Config, metrics *metrics.Metrics) (*MessageProvider, error) {
+	messages, err := loadMessages(config.I18N.Overrides)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load i18n messages: %w", err)
 	}
 
+	location, err := time.LoadLocation(config.I18N.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load time zone: %w", err)
+	}
+
 	return &MessageProvider{
 		messages: messages,
+		location: location,
 		logger:   logger,
+		metrics:  metrics,
 		config:   config,
 	}, nil
 }
@@ -46,20 +61,32 @@ func (mp MessageProvider) MessageReplace(
 	getter func(Messages) string,
 	replacements map[string]string,
 ) string {
+	ctx, span := tracing.Start(ctx, "i18n.MessageReplace", attribute.String("lang", lang))
+	defer span.End()
+
+	return mp.Replace(ctx, mp.Template(ctx, lang, getter), replacements)
+}
+
+// Template returns a localized message given lang and getter, without replacing its templated values.
+func (mp MessageProvider) Template(ctx context.Context, lang string, getter func(Messages) string) string {
 	messages, ok := mp.messages[lang]
 	if !ok {
 		defaultLang := mp.config.I18N.DefaultLang
 		messages = mp.messages[defaultLang]
+		mp.metrics.I18nFallback(metrics.FallbackLang, lang)
 		mp.logger.ErrorContext(
 			ctx,
 			fmt.Sprintf("No messages exist for lang '%s'. Defaulting to lang '%s'", lang, defaultLang),
 		)
 	}
 
-	msg := getter(messages)
+	return getter(messages)
+}
 
+// Replace replaces the templated values in part of a message returned by [MessageProvider.Template].
+func (mp MessageProvider) Replace(ctx context.Context, msg string, replacements map[string]string) string {
 	re := regexp.MustCompile(`\{[^\}]*\}`)
-	msg = re.ReplaceAllStringFunc(msg, func(sub string) string {
+	return re.ReplaceAllStringFunc(msg, func(sub string) string {
 		key := sub[1 : len(sub)-1]
 		val, ok := replacements[key]
 		if !ok {
@@ -68,6 +95,19 @@ func (mp MessageProvider) MessageReplace(
 		}
 		return val
 	})
+}
+
+// Langs returns the languages messages were loaded for.
+func (mp MessageProvider) Langs() []string {
+	return slices.Sorted(maps.Keys(mp.messages))
+}
+
+// Local returns t in the configured time zone.
+func (mp MessageProvider) Local(t time.Time) time.Time {
+	return t.In(mp.location)
+}
 
-	return msg
+// FormatTime formats t as a date and time in the configured time zone.
+func (mp MessageProvider) FormatTime(t time.Time) string {
+	return t.In(mp.location).Format("2006-01-02 15:04")
 }
@@ -15,7 +15,7 @@ import (
 func Test_Message_en(t *testing.T) {
 	t.Parallel()
 
-	mp, err := i18n.NewMessageProvider(slog.Default(), config.Config{}) //nolint:exhaustruct
+	mp, err := i18n.NewMessageProvider(slog.Default(), config.Config{}, nil) //nolint:exhaustruct
 	if err != nil {
 		t.Errorf("Failed to load message provider: %s", err)
 	}
@@ -33,7 +33,7 @@ func Test_Message_en(t *testing.T) {
 func Test_Message_fr(t *testing.T) {
 	t.Parallel()
 
-	mp, err := i18n.NewMessageProvider(slog.Default(), config.Config{}) //nolint:exhaustruct
+	mp, err := i18n.NewMessageProvider(slog.Default(), config.Config{}, nil) //nolint:exhaustruct
 	if err != nil {
 		t.Errorf("Failed to load message provider: %s", err)
 	}
@@ -45,6 +45,32 @@ func Test_Message_fr(t *testing.T) {
 	}
 }
 
+func Test_Message_overrides(t *testing.T) {
+	t.Parallel()
+
+	var conf config.Config
+	conf.I18N.Overrides = map[string]any{
+		"en": map[string]any{"voice": map[string]any{"welcome": "Welcome to Partner Inc."}},
+	}
+
+	mp, err := i18n.NewMessageProvider(slog.Default(), conf, nil)
+	if err != nil {
+		t.Fatalf("Failed to load message provider: %s", err)
+	}
+
+	ctx := context.Background()
+	welcome := mp.Message(ctx, "en", func(m i18n.Messages) string { return m.Voice.Welcome })
+	if diff := cmp.Diff("Welcome to Partner Inc.", welcome); diff != "" {
+		t.Error(diff)
+	}
+
+	// messages that aren't overridden are unchanged
+	hold := mp.Message(ctx, "en", func(m i18n.Messages) string { return m.Voice.PleaseHold })
+	if diff := cmp.Diff("Please hold while we transfer your call.", hold); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func Test_Message_invalidLang(t *testing.T) {
 	t.Parallel()
 
@@ -54,7 +80,7 @@ func Test_Message_invalidLang(t *testing.T) {
 	logBuf := bytes.Buffer{}
 	logger := slog.New(slog.NewTextHandler(&logBuf, nil))
 
-	mp, err := i18n.NewMessageProvider(logger, config)
+	mp, err := i18n.NewMessageProvider(logger, config, nil)
 	if err != nil {
 		t.Errorf("Failed to load message provider: %s", err)
 	}
@@ -77,7 +103,7 @@ func Test_Message_replacementExpected(t *testing.T) {
 	logBuf := bytes.Buffer{}
 	logger := slog.New(slog.NewTextHandler(&logBuf, nil))
 
-	mp, err := i18n.NewMessageProvider(logger, config.Config{}) //nolint:exhaustruct
+	mp, err := i18n.NewMessageProvider(logger, config.Config{}, nil) //nolint:exhaustruct
 	if err != nil {
 		t.Errorf("Failed to load message provider: %s", err)
 	}
@@ -97,7 +123,7 @@ func Test_Message_replacementExpected(t *testing.T) {
 func Test_MessageReplace(t *testing.T) {
 	t.Parallel()
 
-	mp, err := i18n.NewMessageProvider(slog.Default(), config.Config{}) //nolint:exhaustruct
+	mp, err := i18n.NewMessageProvider(slog.Default(), config.Config{}, nil) //nolint:exhaustruct
 	if err != nil {
 		t.Errorf("Failed to load message provider: %s", err)
 	}
@@ -114,13 +140,45 @@ func Test_MessageReplace(t *testing.T) {
 	}
 }
 
+func Test_Template(t *testing.T) {
+	t.Parallel()
+
+	var conf config.Config
+	conf.I18N.Overrides = map[string]any{
+		"en": map[string]any{"voice": map[string]any{
+			"langSelect": `For service in English, press <say-as interpret-as="digits">{digit}</say-as>.`,
+		}},
+	}
+	mp, err := i18n.NewMessageProvider(slog.Default(), conf, nil)
+	if err != nil {
+		t.Fatalf("Failed to load message provider: %s", err)
+	}
+
+	ctx := context.Background()
+	langSelect := mp.Template(ctx, "en", func(m i18n.Messages) string { return m.Voice.LangSelect })
+	want := `For service in English, press <say-as interpret-as="digits">{digit}</say-as>.`
+	if diff := cmp.Diff(want, langSelect); diff != "" {
+		t.Error(diff)
+	}
+
+	// values are replaced in parsed text, so they aren't parsed as SSML
+	parts, err := i18n.ParseSSML(langSelect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digit := mp.Replace(ctx, parts[1].Text, map[string]string{"digit": "<1>"})
+	if diff := cmp.Diff("<1>", digit); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func Test_MessageReplace_InvalidReplacement(t *testing.T) {
 	t.Parallel()
 
 	logBuf := bytes.Buffer{}
 	logger := slog.New(slog.NewTextHandler(&logBuf, nil))
 
-	mp, err := i18n.NewMessageProvider(logger, config.Config{}) //nolint:exhaustruct
+	mp, err := i18n.NewMessageProvider(logger, config.Config{}, nil) //nolint:exhaustruct
 	if err != nil {
 		t.Errorf("Failed to load message provider: %s", err)
 	}
@@ -141,3 +199,120 @@ func Test_MessageReplace_InvalidReplacement(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func Test_MatchesKeyword(t *testing.T) {
+	t.Parallel()
+
+	keywords := i18n.Keywords("Français, French, boîte vocale")
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "Français.", want: true},
+		{text: "francais", want: true},
+		{text: "En français, s'il vous plaît", want: true},
+		{text: "Boite vocale!", want: true},
+		{text: "Frenchie", want: false},
+		{text: "English", want: false},
+		{text: "", want: false},
+	}
+
+	for _, test := range tests {
+		if got := i18n.MatchesKeyword(test.text, keywords); got != test.want {
+			t.Errorf("MatchesKeyword(%q) = %t, want %t", test.text, got, test.want)
+		}
+	}
+}
+
+func Test_AudioAssets(t *testing.T) {
+	t.Parallel()
+
+	var conf config.Config
+	conf.I18N.Overrides = map[string]any{
+		"en": map[string]any{"voice": map[string]any{
+			"welcome":   "[play:welcome.mp3] Welcome to Infotech Ottawa.",
+			"greetings": map[string]any{"sales": "[play:https://example.com/sales.mp3]Welcome to sales."},
+		}},
+	}
+
+	mp, err := i18n.NewMessageProvider(slog.Default(), conf, nil)
+	if err != nil {
+		t.Fatalf("Failed to load message provider: %s", err)
+	}
+
+	want := map[string][]string{
+		"en": {"https://example.com/sales.mp3", "welcome.mp3"},
+		"fr": nil,
+	}
+	if diff := cmp.Diff(want, mp.AudioAssets()); diff != "" {
+		t.Error(diff)
+	}
+
+	asset, text := i18n.Audio(mp.Message(context.Background(), "en", func(m i18n.Messages) string {
+		return m.Voice.Welcome
+	}))
+	if asset != "welcome.mp3" || text != "Welcome to Infotech Ottawa." {
+		t.Errorf("Audio() = %q, %q", asset, text)
+	}
+}
+
+func Test_ParseSSML(t *testing.T) {
+	t.Parallel()
+
+	got, err := i18n.ParseSSML(`Welcome to <phoneme alphabet="ipa" ph="ˈɪnfoʊtɛk">Infotech</phoneme>.<break time="1s"/>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []i18n.SSML{
+		{Tag: "", Text: "Welcome to ", Attrs: nil},
+		{Tag: "phoneme", Text: "Infotech", Attrs: map[string]string{"alphabet": "ipa", "ph": "ˈɪnfoʊtɛk"}},
+		{Tag: "", Text: ".", Attrs: nil},
+		{Tag: "break", Text: "", Attrs: map[string]string{"time": "1s"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, msg := range []string{
+		`Hello <audio src="https://example.com/hello.mp3"/>`, // tag not allowed
+		`Hello <break speed="slow"/>`,                        // attribute not allowed
+		`Hello <say-as interpret-as="characters"><break/>IT</say-as>`,
+		`Hello <break time="1s">`,
+		`Hello & welcome`,
+	} {
+		if _, err := i18n.ParseSSML(msg); err == nil {
+			t.Errorf("ParseSSML(%q): expected error", msg)
+		}
+	}
+
+	// messages are validated when loaded
+	var conf config.Config
+	conf.I18N.Overrides = map[string]any{
+		"en": map[string]any{"voice": map[string]any{"welcome": `Welcome<prosody rate="slow">!</prosody>`}},
+	}
+	if _, err := i18n.NewMessageProvider(slog.Default(), conf, nil); err == nil {
+		t.Error("NewMessageProvider() with invalid SSML: expected error")
+	}
+}
+
+func Test_IsKeyword(t *testing.T) {
+	t.Parallel()
+
+	keywords := []string{"back", "do not disturb"}
+	tests := map[string]bool{
+		"Back":                  true,
+		"Do not disturb.":       true,
+		"I'll be back at 3":     false,
+		"back soon":             false,
+		"":                      false,
+		"do   NOT disturb!!":    true,
+		"please do not disturb": false,
+	}
+
+	for text, want := range tests {
+		if got := i18n.IsKeyword(text, keywords); got != want {
+			t.Errorf("IsKeyword(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package i18n
+
+import (
+	"strings"
+	"unicode"
+)
+
+//nolint:gochecknoglobals
+var accentReplacer = strings.NewReplacer(
+	"à", "a", "â", "a", "ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "ô", "o", "ù", "u", "û", "u", "ü", "u",
+)
+
+// Keywords splits an i18n message containing a comma-separated list of keywords.
+func Keywords(msg string) []string {
+	var keywords []string
+	for _, keyword := range strings.Split(msg, ",") {
+		keyword = strings.TrimSpace(keyword)
+		if keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+
+	return keywords
+}
+
+// MatchesKeyword reports whether text, such as a speech recognition result, contains any of keywords.
+// Matching ignores case, accents and punctuation.
+func MatchesKeyword(text string, keywords []string) bool {
+	normalizedText := " " + normalize(text) + " "
+
+	for _, keyword := range keywords {
+		normalizedKeyword := normalize(keyword)
+		if normalizedKeyword != "" && strings.Contains(normalizedText, " "+normalizedKeyword+" ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsKeyword reports whether text, such as a text message, is one of keywords, ignoring case, accents and punctuation.
+func IsKeyword(text string, keywords []string) bool {
+	normalizedText := normalize(text)
+
+	for _, keyword := range keywords {
+		if normalizedText != "" && normalize(keyword) == normalizedText {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalize(text string) string {
+	text = accentReplacer.Replace(strings.ToLower(text))
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, text)
+
+	return strings.Join(strings.Fields(text), " ")
+}
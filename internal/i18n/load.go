@@ -2,7 +2,9 @@ package i18n
 
 import (
 	"embed"
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/go-viper/mapstructure/v2"
@@ -14,7 +16,8 @@ var messagesDir embed.FS
 
 const messagesDirName = "messages"
 
-func loadMessages() (map[string]Messages, error) {
+// loadMessages loads the messages of each language, replacing messages with those in overrides by language.
+func loadMessages(overrides map[string]any) (map[string]Messages, error) {
 	dirEntries, err := messagesDir.ReadDir(messagesDirName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load i18n messages: %w", err)
@@ -28,20 +31,25 @@ func loadMessages() (map[string]Messages, error) {
 		}
 
 		filename := dirEntry.Name()
+		lang := strings.Split(filename, ".")[0]
+
+		override, ok := overrides[lang].(map[string]any)
+		if !ok && overrides[lang] != nil {
+			return nil, fmt.Errorf("i18n overrides for %s must be a map of messages", lang)
+		}
 
-		langMessages, err := loadMessagesFromFile(filename)
+		langMessages, err := loadMessagesFromFile(filename, override)
 		if err != nil {
 			return nil, err
 		}
 
-		lang := strings.Split(filename, ".")[0]
 		messages[lang] = langMessages
 	}
 
 	return messages, nil
 }
 
-func loadMessagesFromFile(filename string) (Messages, error) {
+func loadMessagesFromFile(filename string, override map[string]any) (Messages, error) {
 	file, err := messagesDir.ReadFile(messagesDirName + "/" + filename)
 	if err != nil {
 		return Messages{}, fmt.Errorf("failed to load i18n messages from %s: %w", filename, err)
@@ -53,6 +61,7 @@ func loadMessagesFromFile(filename string) (Messages, error) {
 	if err != nil {
 		return Messages{}, fmt.Errorf("failed to unmarshal %s: %w", filename, err)
 	}
+	merge(rawMap, override)
 
 	var messages Messages
 
@@ -69,5 +78,50 @@ func loadMessagesFromFile(filename string) (Messages, error) {
 		return Messages{}, fmt.Errorf("failed to decode %s: %w", filename, err)
 	}
 
+	// only spoken messages may contain SSML, text and email messages may contain e.g. "&"
+	var errs []error
+	walkStrings(reflect.ValueOf(messages.Voice), func(msg string) {
+		_, text := Audio(msg)
+		if _, err := ParseSSML(text); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	if len(errs) > 0 {
+		return Messages{}, fmt.Errorf("failed to validate %s: %w", filename, errors.Join(errs...))
+	}
+
 	return messages, nil
 }
+
+// merge recursively replaces values in dst with those in src.
+func merge(dst map[string]any, src map[string]any) {
+	for key, srcValue := range src {
+		srcMap, srcIsMap := srcValue.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			merge(dstMap, srcMap)
+			continue
+		}
+		dst[key] = srcValue
+	}
+}
+
+// walkStrings calls fn with each string in value, e.g. each message of a [Messages] struct.
+func walkStrings(value reflect.Value, fn func(string)) {
+	switch value.Kind() { //nolint:exhaustive
+	case reflect.String:
+		fn(value.String())
+	case reflect.Struct:
+		for i := range value.NumField() {
+			walkStrings(value.Field(i), fn)
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			walkStrings(value.MapIndex(key), fn)
+		}
+	case reflect.Slice:
+		for i := range value.Len() {
+			walkStrings(value.Index(i), fn)
+		}
+	}
+}
@@ -15,19 +15,127 @@ type Messages struct {
 			Subject string `json:"subject"`
 			Content string `json:"content"`
 		} `json:"voicemail"`
+		ScheduledCallback struct {
+			Subject string `json:"subject"`
+			Content string `json:"content"`
+		} `json:"scheduledCallback"`
+		WeeklyReport struct {
+			Subject string `json:"subject"`
+			Content string `json:"content"`
+			Hour    string `json:"hour"`  // line of the busiest hours
+			Agent   string `json:"agent"` // line of the calls answered by agent
+			None    string `json:"none"`  // replaces empty lists
+		} `json:"weeklyReport"`
 	} `json:"email"`
 	Messaging struct {
 		Response string `json:"response"`
+		Presence struct {
+			Keywords struct {
+				Available string `json:"available"`
+				Away      string `json:"away"`
+				DND       string `json:"dnd"`
+			} `json:"keywords"`
+			Updated string `json:"updated"`
+			Until   string `json:"until"`
+		} `json:"presence"`
 	} `json:"messaging"`
+	Presence struct {
+		Available string `json:"available"`
+		Away      string `json:"away"`
+		DND       string `json:"dnd"`
+	} `json:"presence"`
 	Voice struct {
-		AcceptCall       string `json:"acceptCall"`
-		ConfirmConnected string `json:"confirmConnected"`
-		LangSelect       string `json:"langSelect"`
-		PleaseHold       string `json:"pleaseHold"`
-		RecordAfterTone  string `json:"recordAfterTone"`
-		ReRecord         string `json:"rerecord"`
-		Voicemail        string `json:"voicemail"`
-		VoicemailRepeat  string `json:"voicemailRepeat"`
-		Welcome          string `json:"welcome"`
+		AcceptCall       string            `json:"acceptCall"`
+		AcceptCallBack   string            `json:"acceptCallBack"` // agent answering a call back
+		AgentPIN         string            `json:"agentPIN"`
+		CallingBack      string            `json:"callingBack"`
+		Closed           string            `json:"closed"`
+		ConfirmConnected string            `json:"confirmConnected"`
+		Greetings        map[string]string `json:"greetings"` // welcome for each profile, keyed by profile ID
+		LangChange       string            `json:"langChange"`
+		LangSelect       string            `json:"langSelect"`
+		Menu             map[string]string `json:"menu"` // prompt for each skill in routing menu, keyed by skill
+		OutboundNumber   string            `json:"outboundNumber"`
+		OutboundRejected string            `json:"outboundRejected"`
+		PINInvalid       string            `json:"pinInvalid"`
+		PINLocked        string            `json:"pinLocked"` // too many incorrect PINs were entered
+		PleaseHold       string            `json:"pleaseHold"`
+		RecordAfterTone  string            `json:"recordAfterTone"`
+		ReRecord         string            `json:"rerecord"`
+		ScreenChallenge  string            `json:"screenChallenge"`
+		TechnicalError   string            `json:"technicalError"` // a handler failed, before a fallback
+		Voicemail        string            `json:"voicemail"`
+		VoicemailRepeat  string            `json:"voicemailRepeat"`
+		Welcome          string            `json:"welcome"`
+		WelcomeBack      string            `json:"welcomeBack"`
+		Speech           struct {
+			Keywords struct {
+				Lang       string `json:"lang"`
+				LangChange string `json:"langChange"`
+				Voicemail  string `json:"voicemail"`
+			} `json:"keywords"`
+			LangChange      string `json:"langChange"`
+			LangSelect      string `json:"langSelect"`
+			Voicemail       string `json:"voicemail"`
+			VoicemailRepeat string `json:"voicemailRepeat"`
+		} `json:"speech"`
+		Presence struct {
+			Current  string `json:"current"`
+			Menu     string `json:"menu"`
+			MenuHint string `json:"menuHint"`
+			Updated  string `json:"updated"`
+		} `json:"presence"`
+		Transfer struct {
+			Adding       string `json:"adding"`
+			Menu         string `json:"menu"`
+			MenuHint     string `json:"menuHint"`
+			Transferring string `json:"transferring"`
+			Unavailable  string `json:"unavailable"`
+		} `json:"transfer"`
+		Voicemails struct { // agents' voicemail menu
+			Count    string `json:"count"`
+			CountOne string `json:"countOne"` // count of a single message
+			Deleted  string `json:"deleted"`
+			End      string `json:"end"`
+			Gone     string `json:"gone"` // the message was deleted or handled by another agent meanwhile
+			Handled  string `json:"handled"`
+			Intro    string `json:"intro"`
+			Menu     string `json:"menu"`
+			MenuHint string `json:"menuHint"`
+			None     string `json:"none"`
+		} `json:"voicemails"`
+		Callbacks struct { // callers scheduling a call back
+			ConfirmNumber string            `json:"confirmNumber"`
+			Days          map[string]string `json:"days"` // keyed by the first 3 letters of the weekday, as in business hours
+			EnterNumber   string            `json:"enterNumber"`
+			InvalidNumber string            `json:"invalidNumber"`
+			NoSlots       string            `json:"noSlots"`
+			Offer         string            `json:"offer"`
+			Scheduled     string            `json:"scheduled"`
+			Slot          string            `json:"slot"`
+			TimeFormat    string            `json:"timeFormat"` // Go time layout of spoken slot times
+			Today         string            `json:"today"`
+			Tomorrow      string            `json:"tomorrow"`
+		} `json:"callbacks"`
 	} `json:"voice"`
 }
+
+// Greeting returns the welcome message for callers of a profile, or the default welcome if it has none.
+func (m Messages) Greeting(profileID string) string {
+	if greeting, ok := m.Voice.Greetings[profileID]; ok {
+		return greeting
+	}
+	return m.Voice.Welcome
+}
+
+// PresenceStatus returns the localized name of an agent presence status.
+func (m Messages) PresenceStatus(status string) string {
+	switch status {
+	case "away":
+		return m.Presence.Away
+	case "dnd":
+		return m.Presence.DND
+	default:
+		return m.Presence.Available
+	}
+}
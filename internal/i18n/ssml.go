@@ -0,0 +1,95 @@
+package i18n
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ssmlAttrs lists the SSML tags allowed in i18n messages, and their allowed attributes.
+//
+//nolint:gochecknoglobals
+var ssmlAttrs = map[string][]string{
+	"break":   {"strength", "time"},
+	"say-as":  {"interpret-as", "format"},
+	"phoneme": {"alphabet", "ph"},
+}
+
+// SSML is a part of a spoken i18n message: either text, or one of the SSML tags allowed in messages.
+type SSML struct {
+	Tag   string            // empty for text
+	Text  string            // the text, or the words wrapped by the tag
+	Attrs map[string]string // the tag's attributes
+}
+
+// ParseSSML splits a spoken i18n message into text and SSML tags.
+// Only <break>, <say-as> and <phoneme> tags are allowed, with their text-only contents.
+func ParseSSML(msg string) ([]SSML, error) {
+	if !strings.ContainsAny(msg, "<&") {
+		return []SSML{{Tag: "", Text: msg, Attrs: nil}}, nil
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader("<speak>" + msg + "</speak>"))
+	decoder.Strict = true
+
+	var (
+		parts []SSML
+		tag   *SSML // the tag being parsed, if any
+		depth int
+	)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSML in %q: %w", msg, err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				continue // <speak>
+			}
+			if tag != nil {
+				return nil, fmt.Errorf("invalid SSML in %q: <%s> can't be nested", msg, token.Name.Local)
+			}
+			allowed, ok := ssmlAttrs[token.Name.Local]
+			if !ok {
+				return nil, fmt.Errorf("invalid SSML in %q: <%s> isn't allowed", msg, token.Name.Local)
+			}
+			tag = &SSML{Tag: token.Name.Local, Text: "", Attrs: map[string]string{}}
+			for _, attr := range token.Attr {
+				if !slices.Contains(allowed, attr.Name.Local) {
+					return nil, fmt.Errorf("invalid SSML in %q: <%s> has no %s attribute",
+						msg, token.Name.Local, attr.Name.Local)
+				}
+				tag.Attrs[attr.Name.Local] = attr.Value
+			}
+		case xml.EndElement:
+			depth--
+			if tag != nil {
+				if tag.Tag == "break" && tag.Text != "" {
+					return nil, fmt.Errorf("invalid SSML in %q: <break> must be empty", msg)
+				}
+				parts = append(parts, *tag)
+				tag = nil
+			}
+		case xml.CharData:
+			switch {
+			case tag != nil:
+				tag.Text += string(token)
+			case len(parts) > 0 && parts[len(parts)-1].Tag == "":
+				parts[len(parts)-1].Text += string(token)
+			default:
+				parts = append(parts, SSML{Tag: "", Text: string(token), Attrs: nil})
+			}
+		}
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,63 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/httputil"
+)
+
+type accessKey struct{}
+
+// accessEntry collects attributes of a request's access log entry while it is served.
+type accessEntry struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// Annotate adds attrs to the access log entry of the request being served with ctx, if any.
+func Annotate(ctx context.Context, attrs ...slog.Attr) {
+	entry, ok := ctx.Value(accessKey{}).(*accessEntry)
+	if !ok {
+		return
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.attrs = append(entry.attrs, attrs...)
+}
+
+// AccessLog logs an entry for each request served by h, with its route pattern, status and latency,
+// and the attributes handlers annotated it with (e.g. whether its Twilio signature was valid).
+// Requests must be served by [Middleware] for handlers to annotate them.
+func AccessLog(logger *slog.Logger, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := httputil.NewStatusRecorder(w)
+
+		h.ServeHTTP(rec, r)
+
+		ctx := r.Context()
+		attrs := []slog.Attr{
+			// recognized by Cloud Logging
+			slog.Group("httpRequest",
+				slog.String("requestMethod", r.Method),
+				slog.String("requestUrl", r.URL.Path), // query strings carry phone numbers and signatures
+				slog.Int("status", rec.Status),
+				slog.String("latency", fmt.Sprintf("%.3fs", time.Since(start).Seconds())),
+			),
+			slog.String("route", r.Pattern), // set by the mux while serving the request
+		}
+		if entry, ok := ctx.Value(accessKey{}).(*accessEntry); ok {
+			entry.mu.Lock()
+			attrs = append(attrs, entry.attrs...)
+			entry.mu.Unlock()
+		}
+
+		logger.LogAttrs(ctx, slog.LevelInfo, "Served HTTP request", attrs...)
+	})
+}
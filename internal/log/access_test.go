@@ -0,0 +1,49 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(contextHandler{slog.NewJSONHandler(&buf, nil)})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /voice/{action}", func(w http.ResponseWriter, r *http.Request) {
+		Annotate(r.Context(), slog.Bool("signatureValid", false))
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	handler := Middleware(AccessLog(logger, mux))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/voice/inbound", nil))
+
+	var got struct {
+		HTTPRequest struct {
+			RequestMethod string `json:"requestMethod"`
+			Status        int    `json:"status"`
+		} `json:"httpRequest"`
+		Route          string `json:"route"`
+		SignatureValid *bool  `json:"signatureValid"`
+	}
+	err := json.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.HTTPRequest.RequestMethod != http.MethodPost || got.HTTPRequest.Status != http.StatusUnauthorized {
+		t.Errorf("Access log httpRequest = %+v, want a POST with status 401", got.HTTPRequest)
+	}
+	if got.Route != "POST /voice/{action}" {
+		t.Errorf("Access log route = %q, want the route pattern", got.Route)
+	}
+	if got.SignatureValid == nil || *got.SignatureValid {
+		t.Errorf("Access log signatureValid = %v, want false", got.SignatureValid)
+	}
+}
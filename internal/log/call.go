@@ -0,0 +1,73 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// callParams are the Twilio webhook parameters identifying the call or message a request is about.
+// A call's webhooks are separate requests, so these correlate all the logs of a call.
+var callParams = []string{"CallSid", "ParentCallSid", "MessageSid", "AccountSid"} //nolint:gochecknoglobals
+
+type attrsKey struct{}
+
+// ContextWith returns a copy of ctx whose log records all carry attrs, in addition to those already in ctx.
+func ContextWith(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := attrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+
+	return attrs
+}
+
+// Middleware applies logging middleware to an [http.Handler] which adds the Twilio identifiers
+// of the call or message a webhook is about (e.g. CallSid) to request context,
+// so that all log entries of a call can be found, and lets handlers [Annotate] the request's access log entry.
+//
+// Middleware serves a copy of requests, so middleware relying on the route pattern
+// set by an [http.ServeMux] (e.g. [AccessLog]) must be applied inside it.
+func Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		// handlers report malformed forms
+		_ = req.ParseForm()
+
+		var attrs []slog.Attr
+		for _, param := range callParams {
+			if value := req.Form.Get(param); value != "" {
+				attrs = append(attrs, slog.String(strings.ToLower(param[:1])+param[1:], value))
+			}
+		}
+		ctx := ContextWith(req.Context(), attrs...)
+		ctx = context.WithValue(ctx, accessKey{}, &accessEntry{}) //nolint:exhaustruct
+
+		handler.ServeHTTP(writer, req.WithContext(ctx))
+	})
+}
+
+// contextHandler adds the attributes in a record's context to the record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	rec.AddAttrs(attrsFromContext(ctx)...)
+
+	return h.Handler.Handle(ctx, rec) //nolint:wrapcheck
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(contextHandler{slog.NewJSONHandler(&buf, nil)}).With("tenant", "acme")
+
+	handler := Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "Ringing agents")
+	}))
+
+	form := url.Values{"CallSid": {"CA123"}, "ParentCallSid": {"CA000"}, "AccountSid": {"AC456"}, "To": {"+15551234567"}}
+	req := httptest.NewRequest(http.MethodPost, "/voice/inbound", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var got map[string]any
+	err := json.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"callSid": "CA123", "parentCallSid": "CA000", "accountSid": "AC456", "tenant": "acme"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("Log record %s = %v, want %q", key, got[key], value)
+		}
+	}
+	for _, key := range []string{"messageSid", "to"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("Log record should not have %s: %v", key, got)
+		}
+	}
+}
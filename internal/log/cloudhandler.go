@@ -2,30 +2,50 @@ package log
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 
 	"github.com/infotecho/ocomms/internal/config"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type cloudLoggingHandler struct {
-	*slog.JSONHandler
+	slog.Handler
+
+	projectID string
 }
 
+// Handle associates log entries with the trace and span of the request being served, if any.
 func (h cloudLoggingHandler) Handle(ctx context.Context, rec slog.Record) error {
-	if trace, ok := traceFromContext(ctx); ok {
-		rec.AddAttrs(slog.String("logging.googleapis.com/trace", trace))
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		if h.projectID != "" {
+			rec.AddAttrs(slog.String(
+				"logging.googleapis.com/trace",
+				fmt.Sprintf("projects/%s/traces/%s", h.projectID, span.TraceID()),
+			))
+		}
+		rec.AddAttrs(
+			slog.String("logging.googleapis.com/spanId", span.SpanID().String()),
+			slog.Bool("logging.googleapis.com/trace_sampled", span.IsSampled()),
+		)
 	}
 
-	return h.JSONHandler.Handle(ctx, rec) //nolint:wrapcheck
+	return h.Handler.Handle(ctx, rec) //nolint:wrapcheck
 }
 
-func newCloudLoggingHandler(conf config.Config) cloudLoggingHandler {
+func newCloudLoggingHandler(conf config.Config, w io.Writer) cloudLoggingHandler {
+	redactor := newRedactor(conf)
+
 	return cloudLoggingHandler{
-		slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		projectID: os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{
 			AddSource: true,
 			Level:     conf.Logging.Level,
-			ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+				attr = redactor.replaceAttr(groups, attr)
+
 				switch attr.Key {
 				case slog.MessageKey:
 					attr.Key = "message"
@@ -40,3 +60,13 @@ func newCloudLoggingHandler(conf config.Config) cloudLoggingHandler {
 		}),
 	}
 }
+
+// WithAttrs keeps logs of loggers with attributes associated with their traces.
+func (h cloudLoggingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return cloudLoggingHandler{Handler: h.Handler.WithAttrs(attrs), projectID: h.projectID}
+}
+
+// WithGroup keeps logs of loggers with groups associated with their traces.
+func (h cloudLoggingHandler) WithGroup(name string) slog.Handler {
+	return cloudLoggingHandler{Handler: h.Handler.WithGroup(name), projectID: h.projectID}
+}
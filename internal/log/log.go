@@ -3,22 +3,34 @@
 package log
 
 import (
+	"io"
 	"log/slog"
+	"os"
 
 	"github.com/infotecho/ocomms/internal/config"
 )
 
-// New creates a new [slog.Logger] according to application config.
+// New creates a new [slog.Logger] writing to stderr according to application config, and makes it the default.
 func New(conf config.Config) *slog.Logger {
+	logger := NewWriter(conf, os.Stderr)
+	slog.SetDefault(logger)
+
+	return logger
+}
+
+// NewWriter creates a new [slog.Logger] writing to w according to application config.
+func NewWriter(conf config.Config, w io.Writer) *slog.Logger {
+	var handler slog.Handler
 	switch conf.Logging.Format {
 	case config.LogFormatText:
-		// use default logger
+		handler = slog.NewTextHandler(w, &slog.HandlerOptions{ //nolint:exhaustruct
+			Level:       conf.Logging.Level,
+			ReplaceAttr: newRedactor(conf).replaceAttr,
+		})
 	default:
 		// JSON is default to ensure that logs in live environments are always formatted correctly
-		handler := newCloudLoggingHandler(conf)
-		logger := slog.New(handler)
-		slog.SetDefault(logger)
+		handler = newCloudLoggingHandler(conf, w)
 	}
 
-	return slog.Default()
+	return slog.New(contextHandler{handler})
 }
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/infotecho/ocomms/internal/config"
+)
+
+// MessageBodyKey is the key of attributes holding text message bodies, which are redacted as a whole.
+const MessageBodyKey = "messageBody"
+
+// maskKeptDigits is the number of trailing digits left unmasked in phone numbers, as in [phone.Mask].
+const maskKeptDigits = 4
+
+var (
+	// E.164 numbers, also URL-encoded (e.g. in the URLs of Twilio API errors),
+	// and North American numbers written without their country code, e.g. in text messages
+	//nolint:gochecknoglobals
+	phoneNumberRe = regexp.MustCompile(
+		`(?:\+|%2[Bb])[1-9]\d{6,14}|(?:\([2-9]\d{2}\) ?|\b[2-9]\d{2}[-. ]?)\d{3}[-. ]?\d{4}\b`,
+	)
+	//nolint:gochecknoglobals
+	emailRe = regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)
+)
+
+// redactor masks personal information in log entries according to the configured policy.
+type redactor struct {
+	phoneNumbers  bool
+	messageBodies bool
+	emails        bool
+}
+
+func newRedactor(conf config.Config) redactor {
+	return redactor{
+		phoneNumbers:  conf.Logging.Redact.PhoneNumbers,
+		messageBodies: conf.Logging.Redact.MessageBodies,
+		emails:        conf.Logging.Redact.Emails,
+	}
+}
+
+// replaceAttr is a [slog.HandlerOptions] ReplaceAttr function redacting attributes, log messages included.
+func (r redactor) replaceAttr(_ []string, attr slog.Attr) slog.Attr {
+	if r.messageBodies && attr.Key == MessageBodyKey {
+		return slog.String(attr.Key, "[redacted]")
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(r.redact(attr.Value.String()))
+	case slog.KindAny:
+		// e.g. errors or lists of numbers, only formatted if they need redaction
+		text := fmt.Sprint(attr.Value.Any())
+		if redacted := r.redact(text); redacted != text {
+			attr.Value = slog.StringValue(redacted)
+		}
+	default:
+	}
+
+	return attr
+}
+
+func (r redactor) redact(text string) string {
+	if r.phoneNumbers {
+		text = phoneNumberRe.ReplaceAllStringFunc(text, maskDigits)
+	}
+	if r.emails {
+		text = emailRe.ReplaceAllStringFunc(text, func(email string) string {
+			local, domain, _ := strings.Cut(email, "@")
+			return local[:1] + "***@" + domain
+		})
+	}
+	return text
+}
+
+// maskDigits masks all but the last digits of a phone number matched by phoneNumberRe, keeping its punctuation.
+func maskDigits(number string) string {
+	prefix := ""
+	if len(number) > 3 && strings.EqualFold(number[:3], "%2B") {
+		prefix, number = number[:3], number[3:]
+	}
+
+	masked := []byte(number)
+	kept := 0
+	for i := len(masked) - 1; i >= 0; i-- {
+		if masked[i] < '0' || masked[i] > '9' {
+			continue
+		}
+		if kept < maskKeptDigits {
+			kept++
+		} else {
+			masked[i] = '*'
+		}
+	}
+	return prefix + string(masked)
+}
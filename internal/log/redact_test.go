@@ -0,0 +1,105 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/log"
+)
+
+func redactAll() config.Config {
+	var conf config.Config
+	conf.Logging.Redact.PhoneNumbers = true
+	conf.Logging.Redact.MessageBodies = true
+	conf.Logging.Redact.Emails = true
+	return conf
+}
+
+func TestRedaction(t *testing.T) {
+	t.Parallel()
+
+	logEntry := func(conf config.Config) string {
+		var buf bytes.Buffer
+		logger := log.NewWriter(conf, &buf).With("agent", "+16137775651")
+		ctx := log.ContextWith(context.Background(), slog.String("to", "+16137775650"))
+
+		logger.ErrorContext(ctx, "Text message from +17052223434",
+			log.MessageBodyKey, "Call me back at 613-555-0100",
+			"email", "caleb@infotechottawa.ca",
+			"err", errors.New("failed to send email to caleb@infotechottawa.ca"),
+			"dids", []string{"+17778889999"},
+			"national", "Call (613) 555-0101 or 6135550102",
+			"url", "https://api.twilio.com/2010-04-01/Calls.json?To=%2B16135550103",
+			"callSid", "CA1234567890123456789012345678901a",
+			"attempts", 2,
+		)
+		return buf.String()
+	}
+
+	got := logEntry(redactAll())
+	for _, want := range []string{
+		`"message":"Text message from +*******3434"`,
+		`"agent":"+*******5651"`,
+		`"to":"+*******5650"`,
+		`"messageBody":"[redacted]"`,
+		`"email":"c***@infotechottawa.ca"`,
+		`"err":"failed to send email to c***@infotechottawa.ca"`,
+		`"dids":"[+*******9999]"`,
+		`"national":"Call (***) ***-0101 or ******0102"`,
+		`"url":"https://api.twilio.com/2010-04-01/Calls.json?To=%2B*******0103"`,
+		`"callSid":"CA1234567890123456789012345678901a"`,
+		`"attempts":2`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Redacted log should contain %s, got: %s", want, got)
+		}
+	}
+
+	unredacted := logEntry(config.Config{}) //nolint:exhaustruct
+	for _, want := range []string{
+		"+17052223434", "613-555-0100", "caleb@infotechottawa.ca", `"dids":["+17778889999"]`, "%2B16135550103",
+	} {
+		if !strings.Contains(unredacted, want) {
+			t.Errorf("Unredacted log should contain %s, got: %s", want, unredacted)
+		}
+	}
+}
+
+func TestRedaction_accessLog(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := log.NewWriter(redactAll(), &buf)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /lookup/{number}", func(w http.ResponseWriter, r *http.Request) {
+		log.Annotate(r.Context(), slog.String("caller", r.PathValue("number")))
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := log.Middleware(log.AccessLog(logger, mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/%2B16135550100?MessageSid=SM123", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := buf.String()
+	for _, want := range []string{
+		`"requestUrl":"/lookup/+*******0100"`,
+		`"caller":"+*******0100"`,
+		`"messageSid":"SM123"`,
+		`"status":404`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Access log entry should contain %s, got: %s", want, got)
+		}
+	}
+	if strings.Contains(got, "6135550100") {
+		t.Errorf("Access log entry should not contain the caller's number, got: %s", got)
+	}
+}
@@ -3,13 +3,25 @@ package mail
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/infotecho/ocomms/internal/agents"
+	"github.com/infotecho/ocomms/internal/callback"
 	"github.com/infotecho/ocomms/internal/config"
 	"github.com/infotecho/ocomms/internal/i18n"
+	"github.com/infotecho/ocomms/internal/metrics"
+	"github.com/infotecho/ocomms/internal/profiles"
+	"github.com/infotecho/ocomms/internal/report"
+	"github.com/infotecho/ocomms/internal/tracing"
 	"github.com/sendgrid/rest"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 // SendGridClient is an interface for [github.com/sendgrid/sendgrid-go.Client].
@@ -22,11 +34,18 @@ type SendGridMailer struct {
 	Config         config.Config
 	I18n           *i18n.MessageProvider
 	Logger         *slog.Logger
+	Metrics        *metrics.Metrics
 	SendGridClient SendGridClient
 }
 
 // TextMessage notifies agents that a client send a text message.
-func (m *SendGridMailer) TextMessage(ctx context.Context, lang string, fromDID string, messageBody string) {
+func (m *SendGridMailer) TextMessage(
+	ctx context.Context,
+	lang string,
+	profile config.Profile,
+	fromDID string,
+	messageBody string,
+) {
 	subject := m.I18n.MessageReplace(
 		ctx,
 		lang,
@@ -44,11 +63,18 @@ func (m *SendGridMailer) TextMessage(ctx context.Context, lang string, fromDID s
 		},
 	)
 
-	m.send(ctx, subject, content)
+	m.send(ctx, subject, content, m.recipients(profile, func(a config.Agent) bool { return a.Notifications.TextMessage }))
 }
 
 // Voicemail notifies agents by email that a client left a voicemail.
-func (m *SendGridMailer) Voicemail(ctx context.Context, lang string, fromDID string, recordingSID string) {
+func (m *SendGridMailer) Voicemail(
+	ctx context.Context,
+	lang string,
+	profile config.Profile,
+	fromDID string,
+	toDID string,
+	recordingSID string,
+) {
 	subject := m.I18n.MessageReplace(
 		ctx,
 		lang,
@@ -63,22 +89,166 @@ func (m *SendGridMailer) Voicemail(ctx context.Context, lang string, fromDID str
 		func(m i18n.Messages) string { return m.Email.Voicemail.Content },
 		map[string]string{
 			"phoneNumber":  fromDID,
-			"voicemailURL": "https://ocomms-539601029037.northamerica-northeast1.run.app/recordings/" + recordingSID,
+			"voicemailURL": m.Config.Server.BaseURL + "/recordings/" + recordingSID,
+			"callbackURL":  callback.Link(m.Config, toDID, fromDID, time.Now()),
 		},
 	)
 
-	m.send(ctx, subject, content)
+	m.send(ctx, subject, content, m.recipients(profile, func(a config.Agent) bool { return a.Notifications.Voicemail }))
 }
 
-func (m *SendGridMailer) send(ctx context.Context, subject string, content string) {
+// ScheduledCallback notifies agents by email that a caller scheduled a call back at slot.
+func (m *SendGridMailer) ScheduledCallback(
+	ctx context.Context,
+	lang string,
+	profile config.Profile,
+	number string,
+	toDID string,
+	slot time.Time,
+) {
+	subject := m.I18n.MessageReplace(
+		ctx,
+		lang,
+		func(m i18n.Messages) string { return m.Email.ScheduledCallback.Subject },
+		map[string]string{
+			"phoneNumber": number,
+		},
+	)
+	content := m.I18n.MessageReplace(
+		ctx,
+		lang,
+		func(m i18n.Messages) string { return m.Email.ScheduledCallback.Content },
+		map[string]string{
+			"phoneNumber": number,
+			"time":        m.I18n.FormatTime(slot),
+			"callbackURL": callback.Link(m.Config, toDID, number, time.Now()),
+		},
+	)
+
+	m.send(ctx, subject, content, m.recipients(profile, func(a config.Agent) bool {
+		return a.Notifications.ScheduledCallback
+	}))
+}
+
+// WeeklyReport emails a summary of last week's activity to the configured report recipients.
+func (m *SendGridMailer) WeeklyReport(ctx context.Context, lang string, summary report.Summary) {
+	const dateLayout = "2006-01-02"
+	period := map[string]string{
+		"from": m.I18n.Local(summary.From).Format(dateLayout),
+		"to":   m.I18n.Local(summary.To.Add(-time.Nanosecond)).Format(dateLayout), // the last day, as To is exclusive
+	}
+
+	var hours []string
+	for _, hour := range summary.BusiestHours {
+		hours = append(hours, m.I18n.MessageReplace(ctx, lang,
+			func(m i18n.Messages) string { return m.Email.WeeklyReport.Hour },
+			map[string]string{"hour": fmt.Sprintf("%02d:00", hour.Hour), "calls": strconv.Itoa(hour.Calls)},
+		))
+	}
+
+	var answeredBy []string
+	for _, agent := range summary.Agents {
+		name := agent.Agent
+		if a, ok := agents.ByID(m.Config.Agents, agent.Agent); ok && a.Name != "" {
+			name = a.Name
+		}
+		answeredBy = append(answeredBy, m.I18n.MessageReplace(ctx, lang,
+			func(m i18n.Messages) string { return m.Email.WeeklyReport.Agent },
+			map[string]string{"agent": name, "calls": strconv.Itoa(agent.Calls)},
+		))
+	}
+
+	list := func(lines []string) string {
+		if len(lines) == 0 {
+			return m.I18n.Message(ctx, lang, func(m i18n.Messages) string { return m.Email.WeeklyReport.None })
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	subject := m.I18n.MessageReplace(ctx, lang, func(m i18n.Messages) string { return m.Email.WeeklyReport.Subject }, period)
+	content := m.I18n.MessageReplace(
+		ctx,
+		lang,
+		func(m i18n.Messages) string { return m.Email.WeeklyReport.Content },
+		map[string]string{
+			"from":                period["from"],
+			"to":                  period["to"],
+			"calls":               strconv.Itoa(summary.Calls),
+			"blocked":             strconv.Itoa(summary.Blocked),
+			"answerRate":          fmt.Sprintf("%.0f%%", summary.AnswerRate()*100), //nolint:mnd
+			"averageWait":         summary.AverageWait.String(),
+			"voicemails":          strconv.Itoa(summary.Voicemails),
+			"unhandledVoicemails": strconv.Itoa(summary.UnhandledVoicemails),
+			"textMessages":        strconv.Itoa(summary.TextMessages),
+			"busiestHours":        list(hours),
+			"agents":              list(answeredBy),
+		},
+	)
+
+	var to []*mail.Email
+	for _, address := range m.Config.Reports.Weekly.MailTo {
+		to = append(to, mail.NewEmail("", address))
+	}
+	if len(to) == 0 {
+		to = append(to, mail.NewEmail(m.Config.Mail.To.Name, m.Config.Mail.To.Address))
+	}
+
+	m.send(ctx, subject, content, to)
+}
+
+// recipients returns the profile's mail recipients if it has any, otherwise the email addresses
+// of the profile's agents who opted in to a notification, or the configured default recipient if no agent did.
+func (m *SendGridMailer) recipients(profile config.Profile, optedIn func(config.Agent) bool) []*mail.Email {
+	var recipients []*mail.Email
+	for _, address := range profile.MailTo {
+		recipients = append(recipients, mail.NewEmail("", address))
+	}
+	if len(recipients) > 0 {
+		return recipients
+	}
+
+	for _, agent := range profiles.Agents(profile, m.Config.Agents) {
+		if agent.Email != "" && optedIn(agent) {
+			recipients = append(recipients, mail.NewEmail(agent.Name, agent.Email))
+		}
+	}
+
+	if len(recipients) == 0 {
+		recipients = append(recipients, mail.NewEmail(m.Config.Mail.To.Name, m.Config.Mail.To.Address))
+	}
+
+	return recipients
+}
+
+func (m *SendGridMailer) send(ctx context.Context, subject string, content string, to []*mail.Email) {
 	mailFrom := mail.NewEmail(m.Config.Mail.From.Name, m.Config.Mail.From.Address)
-	mailTo := mail.NewEmail(m.Config.Mail.To.Name, m.Config.Mail.To.Address)
 
-	email := mail.NewSingleEmailPlainText(mailFrom, subject, mailTo, content)
+	personalization := mail.NewPersonalization()
+	personalization.AddTos(to...)
+
+	email := mail.NewV3Mail()
+	email.SetFrom(mailFrom)
+	email.Subject = subject
+	email.AddPersonalizations(personalization)
+	email.AddContent(mail.NewContent("text/plain", content))
 
+	ctx, span := tracing.Start(ctx, "sendgrid.send")
+	defer span.End()
+
+	start := time.Now()
 	res, err := m.SendGridClient.SendWithContext(ctx, email)
+	statusCode := 0
+	if res != nil {
+		statusCode = res.StatusCode
+	}
+	m.Metrics.MailSent(time.Since(start), statusCode, err)
+	span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+	if err != nil || statusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, "failed to send email")
+	}
 	if err != nil {
 		m.Logger.ErrorContext(ctx, "Error sending email", "err", err)
+		return // there is no response
 	}
 	if res.StatusCode >= http.StatusBadRequest {
 		m.Logger.ErrorContext(
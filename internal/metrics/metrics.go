@@ -0,0 +1,180 @@
+// Package metrics exposes Prometheus metrics about call flows and the services O-Comms depends on.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/httputil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path is the path metrics are scraped from.
+const Path = "/metrics"
+
+// Outcome is a step reached by an inbound call.
+type Outcome string
+
+// Outcomes of inbound calls.
+const (
+	OutcomeAnswered          Outcome = "answered"
+	OutcomeBusy              Outcome = "busy"      // a ring group was busy
+	OutcomeNoAnswer          Outcome = "no-answer" // nobody in a ring group answered
+	OutcomeVoicemail         Outcome = "voicemail" // the caller left a voicemail
+	OutcomeCallbackScheduled Outcome = "callback-scheduled"
+	OutcomeAbandoned         Outcome = "abandoned" // the caller hung up while agents were rung
+)
+
+// Fallback is a kind of i18n fallback.
+type Fallback string
+
+// Fallbacks from missing i18n resources.
+const (
+	FallbackLang  Fallback = "lang"  // no messages in the requested language, the default language was used
+	FallbackAudio Fallback = "audio" // no recording of an audio prompt, text-to-speech was used
+)
+
+// Metrics records O-Comms metrics in its own registry.
+// A nil *Metrics records nothing, e.g. for tools that don't serve metrics.
+type Metrics struct {
+	registry          *prometheus.Registry
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	signatureFailures *prometheus.CounterVec
+	callOutcomes      *prometheus.CounterVec
+	languages         *prometheus.CounterVec
+	mailDuration      prometheus.Histogram
+	mailResponses     *prometheus.CounterVec
+	i18nFallbacks     *prometheus.CounterVec
+}
+
+// New creates and registers O-Comms metrics, along with Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ocomms_http_requests_total",
+			Help: "HTTP requests by route pattern and status code.",
+		}, []string{"route", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ocomms_http_request_duration_seconds",
+			Help:    "Time to respond to HTTP requests by route pattern.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route"}),
+		signatureFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ocomms_twilio_signature_failures_total",
+			Help: "Webhook requests rejected because of an invalid Twilio signature, by route pattern.",
+		}, []string{"route"}),
+		callOutcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ocomms_call_outcomes_total",
+			Help: "Steps reached by inbound calls: answered, busy, no-answer, voicemail, callback-scheduled or abandoned.",
+		}, []string{"outcome"}),
+		languages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ocomms_languages_chosen_total",
+			Help: "Languages chosen by callers.",
+		}, []string{"lang"}),
+		mailDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ocomms_sendgrid_request_duration_seconds",
+			Help:    "Time to send emails through the SendGrid API.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		mailResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ocomms_sendgrid_responses_total",
+			Help: `SendGrid API responses by status code, or "error" if the request failed.`,
+		}, []string{"code"}),
+		i18nFallbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ocomms_i18n_fallbacks_total",
+			Help: "Missing i18n messages or audio prompts, by kind of fallback and language.",
+		}, []string{"kind", "lang"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}), //nolint:exhaustruct
+		m.requests,
+		m.requestDuration,
+		m.signatureFailures,
+		m.callOutcomes,
+		m.languages,
+		m.mailDuration,
+		m.mailResponses,
+		m.i18nFallbacks,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}) //nolint:exhaustruct
+}
+
+// Middleware counts and times the requests served by h, by the route pattern matched by an [http.ServeMux].
+func (m *Metrics) Middleware(h http.Handler) http.Handler {
+	if m == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := httputil.NewStatusRecorder(w)
+
+		h.ServeHTTP(rec, r)
+
+		route := r.Pattern // set by the mux while serving the request
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requests.WithLabelValues(route, strconv.Itoa(rec.Status)).Inc()
+		m.requestDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// SignatureFailure records a webhook request to route with an invalid Twilio signature.
+func (m *Metrics) SignatureFailure(route string) {
+	if m == nil {
+		return
+	}
+	m.signatureFailures.WithLabelValues(route).Inc()
+}
+
+// CallOutcome records a step reached by an inbound call.
+func (m *Metrics) CallOutcome(outcome Outcome) {
+	if m == nil {
+		return
+	}
+	m.callOutcomes.WithLabelValues(string(outcome)).Inc()
+}
+
+// LanguageChosen records the language a caller chose.
+func (m *Metrics) LanguageChosen(lang string) {
+	if m == nil {
+		return
+	}
+	m.languages.WithLabelValues(lang).Inc()
+}
+
+// MailSent records a request to the SendGrid API, its status code, and whether it failed.
+func (m *Metrics) MailSent(duration time.Duration, statusCode int, err error) {
+	if m == nil {
+		return
+	}
+
+	code := strconv.Itoa(statusCode)
+	if err != nil {
+		code = "error"
+	}
+	m.mailDuration.Observe(duration.Seconds())
+	m.mailResponses.WithLabelValues(code).Inc()
+}
+
+// I18nFallback records a fallback from a missing i18n resource in lang.
+func (m *Metrics) I18nFallback(kind Fallback, lang string) {
+	if m == nil {
+		return
+	}
+	m.i18nFallbacks.WithLabelValues(string(kind), lang).Inc()
+}
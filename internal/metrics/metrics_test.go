@@ -0,0 +1,40 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/infotecho/ocomms/internal/metrics"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	m := metrics.New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/recordings/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.Handle("GET "+metrics.Path, m.Handler())
+	handler := m.Middleware(mux)
+
+	for _, path := range []string{"/recordings/RE1", "/recordings/RE2", "/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, metrics.Path, nil))
+	got := rec.Body.String()
+
+	for _, want := range []string{
+		`ocomms_http_requests_total{code="404",route="/recordings/{id}"} 2`,
+		`ocomms_http_requests_total{code="404",route="unmatched"} 1`,
+		`ocomms_http_request_duration_seconds_count{route="/recordings/{id}"} 2`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Metrics should contain %q, got:\n%s", want, got)
+		}
+	}
+}
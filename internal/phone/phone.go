@@ -0,0 +1,87 @@
+// Package phone normalizes phone numbers, applies outbound dialing policy, and masks numbers for privacy.
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	nanpCountryCode           = "1"
+	nanpNationalNumberLength  = 10
+	nanpInternationalDialCode = "011"
+	maxE164Length             = 15
+
+	// maskKeptDigits is the number of trailing digits kept by Mask, to tell numbers apart.
+	maskKeptDigits = 4
+)
+
+var (
+	// ErrInvalidNumber indicates that a number could not be normalized to E.164 format.
+	ErrInvalidNumber = errors.New("invalid phone number")
+
+	// ErrCountryNotAllowed indicates that a number's country code is not allowed by the dialing policy.
+	ErrCountryNotAllowed = errors.New("country code not allowed")
+
+	// ErrPrefixDenied indicates that a number matches a denied prefix, such as a premium rate number.
+	ErrPrefixDenied = errors.New("number prefix denied")
+)
+
+// NormalizeE164 converts digits entered on a North American keypad into E.164 format.
+// 10-digit numbers are assumed to be NANP numbers, and international numbers may be dialed with 011.
+func NormalizeE164(digits string) (string, error) {
+	digits = strings.TrimSpace(digits)
+
+	var number string
+	switch {
+	case strings.HasPrefix(digits, "+"):
+		number = digits[1:]
+	case strings.HasPrefix(digits, nanpInternationalDialCode):
+		number = strings.TrimPrefix(digits, nanpInternationalDialCode)
+	case len(digits) == nanpNationalNumberLength:
+		number = nanpCountryCode + digits
+	case len(digits) == nanpNationalNumberLength+1 && strings.HasPrefix(digits, nanpCountryCode):
+		number = digits
+	default:
+		return "", ErrInvalidNumber
+	}
+
+	if number == "" || len(number) > maxE164Length || number[0] == '0' || strings.Trim(number, "0123456789") != "" {
+		return "", ErrInvalidNumber
+	}
+
+	return "+" + number, nil
+}
+
+// CheckDialPolicy returns an error if an E.164 number may not be dialed
+// because its country code is not one of allowedCountryCodes, or it starts with one of deniedPrefixes.
+func CheckDialPolicy(number string, allowedCountryCodes []string, deniedPrefixes []string) error {
+	allowed := false
+	for _, countryCode := range allowedCountryCodes {
+		if strings.HasPrefix(number, "+"+strings.TrimPrefix(countryCode, "+")) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return ErrCountryNotAllowed
+	}
+
+	for _, prefix := range deniedPrefixes {
+		if strings.HasPrefix(number, prefix) {
+			return ErrPrefixDenied
+		}
+	}
+
+	return nil
+}
+
+// Mask masks all but the last 4 digits of an E.164 number, keeping the +.
+// Shorter values, such as anonymous callers, are returned unchanged.
+func Mask(number string) string {
+	if !strings.HasPrefix(number, "+") || len(number) <= 1+maskKeptDigits {
+		return number
+	}
+	masked := len(number) - 1 - maskKeptDigits
+	return "+" + strings.Repeat("*", masked) + number[len(number)-maskKeptDigits:]
+}
@@ -0,0 +1,77 @@
+package phone_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/infotecho/ocomms/internal/phone"
+)
+
+func TestNormalizeE164(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		digits  string
+		want    string
+		wantErr error
+	}{
+		{digits: "6137775650", want: "+16137775650", wantErr: nil},
+		{digits: "16137775650", want: "+16137775650", wantErr: nil},
+		{digits: "+16137775650", want: "+16137775650", wantErr: nil},
+		{digits: "011442079460000", want: "+442079460000", wantErr: nil},
+		{digits: "7775650", want: "", wantErr: phone.ErrInvalidNumber},
+		{digits: "26137775650", want: "", wantErr: phone.ErrInvalidNumber},
+		{digits: "613777565*", want: "", wantErr: phone.ErrInvalidNumber},
+		{digits: "011", want: "", wantErr: phone.ErrInvalidNumber},
+		{digits: "", want: "", wantErr: phone.ErrInvalidNumber},
+	}
+
+	for _, test := range tests {
+		got, err := phone.NormalizeE164(test.digits)
+		if got != test.want || !errors.Is(err, test.wantErr) {
+			t.Errorf("NormalizeE164(%q) = %q, %v; want %q, %v", test.digits, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestCheckDialPolicy(t *testing.T) {
+	t.Parallel()
+
+	allowedCountryCodes := []string{"1", "+33"}
+	deniedPrefixes := []string{"+1900", "+1876"}
+
+	tests := []struct {
+		number  string
+		wantErr error
+	}{
+		{number: "+16137775650", wantErr: nil},
+		{number: "+33142685300", wantErr: nil},
+		{number: "+442079460000", wantErr: phone.ErrCountryNotAllowed},
+		{number: "+19005550100", wantErr: phone.ErrPrefixDenied},
+		{number: "+18765550100", wantErr: phone.ErrPrefixDenied},
+	}
+
+	for _, test := range tests {
+		err := phone.CheckDialPolicy(test.number, allowedCountryCodes, deniedPrefixes)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("CheckDialPolicy(%q) = %v, want %v", test.number, err, test.wantErr)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"+16137775650":  "+*******5650",
+		"+442079460000": "+********0000",
+		"anonymous":     "anonymous",
+		"+1234":         "+1234",
+	}
+
+	for number, want := range tests {
+		if got := phone.Mask(number); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", number, got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package presence tracks whether agents are available to take calls.
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+// Status is an agent's availability to take calls.
+type Status = string
+
+const (
+	// StatusAvailable means the agent is dialed for inbound calls.
+	StatusAvailable Status = "available"
+
+	// StatusAway means the agent is not dialed for inbound calls, e.g. while on vacation.
+	StatusAway Status = "away"
+
+	// StatusDND means the agent doesn't want to be disturbed by inbound calls.
+	StatusDND Status = "dnd"
+)
+
+// State is an agent's presence status, which reverts to available after Until if set.
+type State struct {
+	Status Status    `json:"status"`
+	Until  time.Time `json:"until,omitempty"`
+}
+
+// Tracker persists agent presence states, keyed by agent ID.
+type Tracker struct {
+	Store store.Store
+}
+
+func key(agentID string) string {
+	return "presence/" + agentID
+}
+
+// State returns an agent's current presence state. Agents are available unless they set otherwise.
+func (t Tracker) State(ctx context.Context, agentID string) (State, error) {
+	available := State{Status: StatusAvailable, Until: time.Time{}}
+
+	value, ok, err := t.Store.Get(ctx, key(agentID))
+	if err != nil {
+		return available, fmt.Errorf("failed to get presence of agent: %w", err)
+	}
+	if !ok {
+		return available, nil
+	}
+
+	var state State
+	err = json.Unmarshal(value, &state)
+	if err != nil {
+		return available, fmt.Errorf("failed to unmarshal presence of agent: %w", err)
+	}
+
+	if !state.Until.IsZero() && time.Now().After(state.Until) {
+		return available, nil
+	}
+
+	return state, nil
+}
+
+// SetState changes an agent's presence state.
+func (t Tracker) SetState(ctx context.Context, agentID string, state State) error {
+	if state.Status == StatusAvailable {
+		err := t.Store.Delete(ctx, key(agentID))
+		if err != nil {
+			return fmt.Errorf("failed to delete presence of agent: %w", err)
+		}
+		return nil
+	}
+
+	value, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal presence of agent: %w", err)
+	}
+
+	err = t.Store.Set(ctx, key(agentID), value)
+	if err != nil {
+		return fmt.Errorf("failed to set presence of agent: %w", err)
+	}
+
+	return nil
+}
+
+// Available filters agents down to those who are available to take calls.
+// Agents whose presence can't be determined are assumed to be available, so that calls still get through.
+func (t Tracker) Available(ctx context.Context, agents []config.Agent) ([]config.Agent, error) {
+	var (
+		available []config.Agent
+		errs      error
+	)
+
+	for _, agent := range agents {
+		state, err := t.State(ctx, agent.ID)
+		errs = errors.Join(errs, err)
+		if state.Status == StatusAvailable {
+			available = append(available, agent)
+		}
+	}
+
+	return available, errs
+}
+
+// ParseDuration parses a duration such as "30m", "2h" or "3d".
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of days '%s'", days)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,65 @@
+package presence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/presence"
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+func TestAvailable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tracker := presence.Tracker{Store: store.NewMemory()}
+
+	states := map[string]presence.State{
+		"agent1": {Status: presence.StatusAway, Until: time.Time{}},
+		"agent2": {Status: presence.StatusDND, Until: time.Now().Add(time.Hour)},
+		"agent3": {Status: presence.StatusAway, Until: time.Now().Add(-time.Minute)},
+		"agent4": {Status: presence.StatusAvailable, Until: time.Time{}},
+	}
+	agents := make([]config.Agent, 0, len(states))
+	for _, id := range []string{"agent1", "agent2", "agent3", "agent4"} {
+		if err := tracker.SetState(ctx, id, states[id]); err != nil {
+			t.Fatal(err)
+		}
+		agents = append(agents, config.Agent{ID: id}) //nolint:exhaustruct
+	}
+
+	got, err := tracker.Available(ctx, agents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 || got[0].ID != "agent3" || got[1].ID != "agent4" {
+		t.Errorf("Available() = %v, want agent3 and agent4", got)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{s: "30m", want: 30 * time.Minute, wantErr: false},
+		{s: "2h", want: 2 * time.Hour, wantErr: false},
+		{s: "3d", want: 72 * time.Hour, wantErr: false},
+		{s: "-1h", want: 0, wantErr: true},
+		{s: "xd", want: 0, wantErr: true},
+		{s: "later", want: 0, wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := presence.ParseDuration(test.s)
+		if got != test.want || (err != nil) != test.wantErr {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v, error %t", test.s, got, err, test.want, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package profiles selects the configuration that applies to calls and text messages to a company DID.
+package profiles
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/config"
+)
+
+// Select returns the profile configured for a company DID,
+// or a default profile from the global configuration if there is none.
+func Select(conf config.Config, did string) config.Profile {
+	languages := slices.Sorted(maps.Keys(conf.Twilio.Languages))
+	if len(languages) == 0 {
+		languages = []string{conf.I18N.DefaultLang}
+	}
+
+	for _, profile := range conf.Profiles {
+		if slices.Contains(profile.DIDs, did) {
+			if len(profile.Languages) == 0 {
+				profile.Languages = languages
+			}
+			return profile
+		}
+	}
+
+	return config.Profile{
+		ID:        "",
+		DIDs:      nil,
+		Languages: languages,
+		Menu:      conf.Routing.Menu,
+		Agents:    nil,
+		MailTo:    nil,
+		Hours:     nil,
+	}
+}
+
+// Agents returns the agents of roster who take calls for a profile.
+func Agents(profile config.Profile, roster []config.Agent) []config.Agent {
+	if len(profile.Agents) == 0 {
+		return roster
+	}
+
+	var agents []config.Agent
+	for _, agent := range roster {
+		if slices.Contains(profile.Agents, agent.ID) {
+			agents = append(agents, agent)
+		}
+	}
+	return agents
+}
+
+// Open returns whether t falls within a profile's business hours, in t's location.
+func Open(profile config.Profile, t time.Time) (bool, error) {
+	if len(profile.Hours) == 0 {
+		return true, nil
+	}
+
+	day := strings.ToLower(t.Weekday().String()[:3])
+	minute := t.Hour()*60 + t.Minute()
+
+	for _, hours := range profile.Hours {
+		if !slices.Contains(hours.Days, day) {
+			continue
+		}
+
+		open, err := minuteOfDay(hours.Open)
+		if err != nil {
+			return true, err
+		}
+		closing, err := minuteOfDay(hours.Close)
+		if err != nil {
+			return true, err
+		}
+
+		if open <= minute && minute < closing {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// minuteOfDay parses a time of day in 24-hour "15:04" format, allowing "24:00" for the end of the day.
+func minuteOfDay(clock string) (int, error) {
+	hour, minute, ok := strings.Cut(clock, ":")
+	h, errHour := strconv.Atoi(hour)
+	m, errMinute := strconv.Atoi(minute)
+	if !ok || errHour != nil || errMinute != nil || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time of day in business hours: %q", clock)
+	}
+
+	return h*60 + m, nil
+}
@@ -0,0 +1,45 @@
+package profiles_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/profiles"
+)
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	var profile config.Profile
+	profile.Hours = []config.Hours{
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Open: "09:00", Close: "17:00"},
+		{Days: []string{"sat"}, Open: "10:00", Close: "24:00"},
+	}
+
+	tests := []struct {
+		name    string
+		t       time.Time
+		want    bool
+		wantErr bool
+	}{
+		{name: "weekday open", t: time.Date(2024, 11, 4, 9, 0, 0, 0, time.UTC), want: true, wantErr: false},
+		{name: "weekday closing", t: time.Date(2024, 11, 4, 17, 0, 0, 0, time.UTC), want: false, wantErr: false},
+		{name: "weekday night", t: time.Date(2024, 11, 4, 3, 0, 0, 0, time.UTC), want: false, wantErr: false},
+		{name: "saturday midnight", t: time.Date(2024, 11, 9, 23, 59, 0, 0, time.UTC), want: true, wantErr: false},
+		{name: "sunday", t: time.Date(2024, 11, 10, 12, 0, 0, 0, time.UTC), want: false, wantErr: false},
+	}
+
+	for _, test := range tests {
+		got, err := profiles.Open(profile, test.t)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("%s: Open() = %v, %v, want %v (error: %v)", test.name, got, err, test.want, test.wantErr)
+		}
+	}
+
+	profile.Hours = []config.Hours{{Days: []string{"mon"}, Open: "9am", Close: "17:00"}}
+	_, err := profiles.Open(profile, time.Date(2024, 11, 4, 12, 0, 0, 0, time.UTC))
+	if err == nil {
+		t.Error("Open() with invalid hours: expected error")
+	}
+}
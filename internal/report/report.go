@@ -0,0 +1,115 @@
+// Package report summarizes the activity recorded in the call history for management.
+package report
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/history"
+)
+
+// busiestHours is the number of hours of the day listed as the busiest.
+const busiestHours = 3
+
+// Summary is the activity over a period.
+type Summary struct {
+	From                time.Time     `json:"from"`
+	To                  time.Time     `json:"to"`
+	Calls               int           `json:"calls"` // excluding blocked calls
+	Blocked             int           `json:"blocked"`
+	Answered            int           `json:"answered"`
+	AverageWait         time.Duration `json:"averageWait"` // until answered calls were answered
+	Voicemails          int           `json:"voicemails"`
+	UnhandledVoicemails int           `json:"unhandledVoicemails"` // voicemails no agent has taken care of yet
+	TextMessages        int           `json:"textMessages"`
+	BusiestHours        []HourCount   `json:"busiestHours"` // hours of the day with the most calls, busiest first
+	Agents              []AgentCount  `json:"agents"`       // calls answered by each agent who answered any, most first
+}
+
+// HourCount is the number of calls started during an hour of the day.
+type HourCount struct {
+	Hour  int `json:"hour"` // 0 to 23, in local time
+	Calls int `json:"calls"`
+}
+
+// AgentCount is the number of calls an agent answered.
+type AgentCount struct {
+	Agent string `json:"agent"` // ID of the agent
+	Calls int    `json:"calls"`
+}
+
+// AnswerRate returns the fraction of calls answered by an agent, or 0 without calls.
+func (s Summary) AnswerRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Answered) / float64(s.Calls)
+}
+
+// Summarize summarizes the calls and text messages recorded from from until to.
+// Busiest hours are counted in loc. A voicemail is unhandled if unhandled reports so for its recording SID.
+func Summarize(
+	from time.Time,
+	to time.Time,
+	loc *time.Location,
+	calls []history.Call,
+	messages []history.Message,
+	unhandled func(recordingSid string) bool,
+) Summary {
+	summary := Summary{From: from, To: to, TextMessages: len(messages)} //nolint:exhaustruct
+
+	var hours [24]int
+	var wait time.Duration
+	answeredBy := make(map[string]int)
+	for _, call := range calls {
+		if call.Outcome == history.OutcomeBlocked {
+			summary.Blocked++
+			continue
+		}
+
+		summary.Calls++
+		hours[call.Start.In(loc).Hour()]++
+
+		switch call.Outcome {
+		case history.OutcomeAnswered:
+			summary.Answered++
+			wait += call.Wait
+			if call.Agent != "" {
+				answeredBy[call.Agent]++
+			}
+		case history.OutcomeVoicemail:
+			summary.Voicemails++
+			if unhandled(call.RecordingSid) {
+				summary.UnhandledVoicemails++
+			}
+		}
+	}
+
+	if summary.Answered > 0 {
+		summary.AverageWait = (wait / time.Duration(summary.Answered)).Round(time.Second)
+	}
+
+	for hour, count := range hours {
+		if count > 0 {
+			summary.BusiestHours = append(summary.BusiestHours, HourCount{Hour: hour, Calls: count})
+		}
+	}
+	slices.SortStableFunc(summary.BusiestHours, func(a, b HourCount) int { return cmp.Compare(b.Calls, a.Calls) })
+	summary.BusiestHours = summary.BusiestHours[:min(len(summary.BusiestHours), busiestHours)]
+
+	for agent, count := range answeredBy {
+		summary.Agents = append(summary.Agents, AgentCount{Agent: agent, Calls: count})
+	}
+	slices.SortFunc(summary.Agents, func(a, b AgentCount) int {
+		return cmp.Or(cmp.Compare(b.Calls, a.Calls), cmp.Compare(a.Agent, b.Agent))
+	})
+
+	return summary
+}
+
+// LastWeek returns the 7 days before the start of now's day, in now's location.
+func LastWeek(now time.Time) (from time.Time, to time.Time) {
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return to.AddDate(0, 0, -7), to //nolint:mnd
+}
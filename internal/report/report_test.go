@@ -0,0 +1,54 @@
+package report_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/infotecho/ocomms/internal/history"
+	"github.com/infotecho/ocomms/internal/report"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	at := func(day int, hour int) time.Time {
+		return time.Date(2024, 11, day, hour, 15, 0, 0, time.UTC)
+	}
+	from, to := report.LastWeek(at(11, 8))
+
+	calls := []history.Call{
+		{CallSid: "CA1", Start: at(4, 9), Outcome: history.OutcomeAnswered, Agent: "caleb", Wait: 20 * time.Second},
+		{CallSid: "CA2", Start: at(4, 9), Outcome: history.OutcomeAnswered, Agent: "sally", Wait: 40 * time.Second},
+		{CallSid: "CA3", Start: at(5, 9), Outcome: history.OutcomeAnswered, Agent: "caleb", Wait: 30 * time.Second},
+		{CallSid: "CA4", Start: at(5, 14), Outcome: history.OutcomeVoicemail, RecordingSid: "RE1"},
+		{CallSid: "CA5", Start: at(6, 14), Outcome: history.OutcomeVoicemail, RecordingSid: "RE2"},
+		{CallSid: "CA6", Start: at(7, 16), Outcome: history.OutcomeAbandoned},
+		{CallSid: "CA7", Start: at(8, 11), Outcome: history.OutcomeBlocked},
+		{CallSid: "CA8", Start: at(8, 17)}, // hung up in the menus
+	}
+	messages := []history.Message{{MessageSid: "SM1", Time: at(6, 10)}}
+	unhandled := func(recordingSid string) bool { return recordingSid == "RE2" }
+
+	want := report.Summary{
+		From:                time.Date(2024, 11, 4, 0, 0, 0, 0, time.UTC),
+		To:                  time.Date(2024, 11, 11, 0, 0, 0, 0, time.UTC),
+		Calls:               7,
+		Blocked:             1,
+		Answered:            3,
+		AverageWait:         30 * time.Second,
+		Voicemails:          2,
+		UnhandledVoicemails: 1,
+		TextMessages:        1,
+		BusiestHours:        []report.HourCount{{Hour: 9, Calls: 3}, {Hour: 14, Calls: 2}, {Hour: 16, Calls: 1}},
+		Agents:              []report.AgentCount{{Agent: "caleb", Calls: 2}, {Agent: "sally", Calls: 1}},
+	}
+
+	got := report.Summarize(from, to, time.UTC, calls, messages, unhandled)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Summarize() mismatch (-want +got):\n%s", diff)
+	}
+	if rate := got.AnswerRate(); rate != 3.0/7 {
+		t.Errorf("AnswerRate() = %v, want %v", rate, 3.0/7)
+	}
+}
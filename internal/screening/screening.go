@@ -0,0 +1,107 @@
+// Package screening decides whether inbound callers are let through, challenged or blocked.
+package screening
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/infotecho/ocomms/internal/callers"
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+// Action is the outcome of screening a caller.
+type Action = string
+
+const (
+	// ActionAllow lets the caller through.
+	ActionAllow Action = "allow"
+
+	// ActionChallenge requires the caller to press a key to continue, which robocallers typically don't do.
+	ActionChallenge Action = "challenge"
+
+	// ActionBlock rejects the call.
+	ActionBlock Action = "block"
+)
+
+// StirVerstat values sent by Twilio with calls signed using STIR/SHAKEN.
+const (
+	stirPassedPrefix = "TN-Validation-Passed-"
+	stirFailedPrefix = "TN-Validation-Failed"
+)
+
+// Result describes the outcome of screening a caller.
+type Result struct {
+	Action Action
+	Reason string
+}
+
+// Screener screens inbound callers according to application config.
+type Screener struct {
+	Config config.Config
+	Logger *slog.Logger
+	Store  store.Store
+}
+
+// Screen determines what to do with a call from number, given the call's StirVerstat parameter.
+func (s Screener) Screen(number string, stirVerstat string) Result {
+	conf := s.Config.Screening
+
+	switch {
+	case matchesAny(number, conf.Allowlist):
+		return Result{Action: ActionAllow, Reason: "allowlist"}
+	case matchesAny(number, conf.Blocklist):
+		return Result{Action: ActionBlock, Reason: "blocklist"}
+	case callers.Anonymous(number):
+		return Result{Action: conf.Anonymous, Reason: "anonymous"}
+	case !conf.StirShaken.Enabled:
+		return Result{Action: ActionAllow, Reason: ""}
+	case strings.HasPrefix(stirVerstat, stirFailedPrefix):
+		return Result{Action: conf.StirShaken.Failed, Reason: "stir-shaken-failed"}
+	case strings.HasPrefix(stirVerstat, stirPassedPrefix) &&
+		strings.TrimPrefix(stirVerstat, stirPassedPrefix) <= conf.StirShaken.MinAttestation:
+		return Result{Action: ActionAllow, Reason: "stir-shaken-passed"}
+	default:
+		return Result{Action: conf.StirShaken.Unverified, Reason: "stir-shaken-unverified"}
+	}
+}
+
+// RecordBlocked logs and counts a blocked call attempt from number.
+func (s Screener) RecordBlocked(ctx context.Context, number string, reason string) {
+	key := "screening/blocked/" + number
+
+	count := 0
+	value, ok, err := s.Store.Get(ctx, key)
+	if err == nil && ok {
+		count, err = strconv.Atoi(string(value))
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Error getting blocked call count", "err", err)
+	}
+
+	count++
+	err = s.Store.Set(ctx, key, []byte(strconv.Itoa(count)))
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Error setting blocked call count", "err", err)
+	}
+
+	s.Logger.WarnContext(ctx, "Blocked call", "from", number, "reason", reason, "blockedCount", count)
+}
+
+// matchesAny reports whether number matches one of patterns:
+// either an exact phone number, or a prefix followed by '*'.
+func matchesAny(number string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(number, prefix) {
+				return true
+			}
+		} else if number == pattern {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package screening_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/screening"
+	"github.com/infotecho/ocomms/internal/store"
+)
+
+func TestScreen(t *testing.T) {
+	t.Parallel()
+
+	var conf config.Config
+	conf.Screening.Allowlist = []string{"+16135550100", "+1800*"}
+	conf.Screening.Blocklist = []string{"+16135550199", "+1900*", "+1613*"}
+	conf.Screening.Anonymous = screening.ActionChallenge
+	conf.Screening.StirShaken.Enabled = true
+	conf.Screening.StirShaken.MinAttestation = "B"
+	conf.Screening.StirShaken.Failed = screening.ActionBlock
+	conf.Screening.StirShaken.Unverified = screening.ActionChallenge
+
+	screener := screening.Screener{
+		Config: conf,
+		Logger: slog.Default(),
+		Store:  store.NewMemory(),
+	}
+
+	tests := []struct {
+		number      string
+		stirVerstat string
+		want        screening.Action
+	}{
+		{number: "+16135550100", stirVerstat: "", want: screening.ActionAllow},
+		{number: "+18005550100", stirVerstat: "TN-Validation-Failed", want: screening.ActionAllow},
+		{number: "+16135550199", stirVerstat: "TN-Validation-Passed-A", want: screening.ActionBlock},
+		{number: "+19005550100", stirVerstat: "", want: screening.ActionBlock},
+		{number: "+16135550123", stirVerstat: "TN-Validation-Passed-A", want: screening.ActionBlock},
+		{number: "+266696687", stirVerstat: "", want: screening.ActionChallenge},
+		{number: "Anonymous", stirVerstat: "", want: screening.ActionChallenge},
+		{number: "+17055550100", stirVerstat: "TN-Validation-Passed-A", want: screening.ActionAllow},
+		{number: "+17055550100", stirVerstat: "TN-Validation-Passed-B", want: screening.ActionAllow},
+		{number: "+17055550100", stirVerstat: "TN-Validation-Passed-C", want: screening.ActionChallenge},
+		{number: "+17055550100", stirVerstat: "TN-Validation-Failed-B", want: screening.ActionBlock},
+		{number: "+17055550100", stirVerstat: "No-TN-Validation", want: screening.ActionChallenge},
+		{number: "+17055550100", stirVerstat: "", want: screening.ActionChallenge},
+	}
+
+	for _, test := range tests {
+		got := screener.Screen(test.number, test.stirVerstat)
+		if got.Action != test.want {
+			t.Errorf("Screen(%q, %q) = %s, want %s", test.number, test.stirVerstat, got.Action, test.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File is a [Store] keeping each key in a file of a directory, e.g. of a persistent volume,
+// so state outlives the process. Updates are only serialized within the process, so instances sharing
+// the directory may overwrite each other's concurrent updates.
+type File struct {
+	Dir string
+
+	mu sync.RWMutex // orders reads after the writes they follow
+}
+
+// NewFile creates a [Store] in dir, creating it if needed.
+func NewFile(dir string) (*File, error) {
+	if dir == "" {
+		return nil, errors.New("storage.dir is required by the file storage driver")
+	}
+	err := os.MkdirAll(dir, 0o700) //nolint:mnd
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &File{Dir: dir, mu: sync.RWMutex{}}, nil
+}
+
+// path returns the path of the file of key. Keys are escaped to a single file name,
+// so keys from requests (e.g. a CallSid) can't reach outside the directory.
+func (f *File) path(key string) string {
+	return filepath.Join(f.Dir, url.PathEscape(key))
+}
+
+// Get implements [Store.Get].
+func (f *File) Get(_ context.Context, key string) ([]byte, bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	value, err := os.ReadFile(f.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read stored value: %w", err)
+	}
+
+	return value, true, nil
+}
+
+// Set implements [Store.Set]. Values are written to a temporary file first,
+// so a crash never leaves a value half written.
+func (f *File) Set(_ context.Context, key string, value []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tmp, err := os.CreateTemp(f.Dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create stored value: %w", err)
+	}
+	_, err = tmp.Write(value)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write stored value: %w", err)
+	}
+
+	return nil
+}
+
+// Delete implements [Store.Delete].
+func (f *File) Delete(_ context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := os.Remove(f.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete stored value: %w", err)
+	}
+
+	return nil
+}
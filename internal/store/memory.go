@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Memory is an in-memory [Store]. State is lost when the process exits.
+type Memory struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+// NewMemory creates an empty in-memory [Store].
+func NewMemory() *Memory {
+	return &Memory{
+		mu:     sync.RWMutex{},
+		values: map[string][]byte{},
+	}
+}
+
+// Get implements [Store.Get].
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	value, ok := m.values[key]
+
+	return slices.Clone(value), ok, nil
+}
+
+// Set implements [Store.Set].
+func (m *Memory) Set(_ context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = slices.Clone(value)
+
+	return nil
+}
+
+// Delete implements [Store.Delete].
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.values, key)
+
+	return nil
+}
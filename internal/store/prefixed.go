@@ -0,0 +1,24 @@
+package store
+
+import "context"
+
+// Prefixed namespaces the keys of a [Store], e.g. to isolate the state of tenants sharing it.
+type Prefixed struct {
+	Prefix string
+	Store  Store
+}
+
+// Get implements [Store.Get].
+func (p Prefixed) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return p.Store.Get(ctx, p.Prefix+key) //nolint:wrapcheck
+}
+
+// Set implements [Store.Set].
+func (p Prefixed) Set(ctx context.Context, key string, value []byte) error {
+	return p.Store.Set(ctx, p.Prefix+key, value) //nolint:wrapcheck
+}
+
+// Delete implements [Store.Delete].
+func (p Prefixed) Delete(ctx context.Context, key string) error {
+	return p.Store.Delete(ctx, p.Prefix+key) //nolint:wrapcheck
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const dayLayout = "2006-01-02"
+
+// PruneDays calls prune for each UTC day before cutoff that wasn't pruned yet, to delete data kept in daily buckets
+// once it's older than a retention period. The last day pruned is kept at cursorKey. Until it's set,
+// days are pruned from written, the day of the data being written. Data written late for a day already pruned
+// is pruned again.
+func PruneDays(
+	ctx context.Context,
+	store Store,
+	cursorKey string,
+	written time.Time,
+	cutoff time.Time,
+	prune func(ctx context.Context, day time.Time) error,
+) error {
+	writtenDay := written.UTC().Truncate(24 * time.Hour) //nolint:mnd
+	cutoffDay := cutoff.UTC().Truncate(24 * time.Hour)   //nolint:mnd
+	next := writtenDay
+	value, ok, err := store.Get(ctx, cursorKey)
+	if err != nil {
+		return fmt.Errorf("failed to get last day pruned: %w", err)
+	}
+	if ok {
+		last, parseErr := time.Parse(dayLayout, string(value))
+		if parseErr != nil {
+			return fmt.Errorf("failed to parse last day pruned: %w", parseErr)
+		}
+		next = last.AddDate(0, 0, 1)
+
+		if writtenDay.Before(next) && writtenDay.Before(cutoffDay) {
+			err = prune(ctx, writtenDay)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	pruned := false
+	for day := next; day.Before(cutoffDay); day = day.AddDate(0, 0, 1) {
+		err = prune(ctx, day)
+		if err != nil {
+			return err
+		}
+		next, pruned = day.AddDate(0, 0, 1), true
+	}
+	if ok && !pruned {
+		return nil
+	}
+
+	err = store.Set(ctx, cursorKey, []byte(next.AddDate(0, 0, -1).Format(dayLayout)))
+	if err != nil {
+		return fmt.Errorf("failed to set last day pruned: %w", err)
+	}
+	return nil
+}
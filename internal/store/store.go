@@ -0,0 +1,39 @@
+// Package store implements persistence of application state as key-value pairs.
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/infotecho/ocomms/internal/config"
+)
+
+// Store is a key-value store. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored at key, and whether the key exists.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value at key, overwriting any existing value.
+	Set(ctx context.Context, key string, value []byte) error
+	// Delete removes key from the store. Deleting a key that doesn't exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Storage drivers.
+const (
+	StorageDriverMemory = "memory" // state is lost when instances restart, e.g. for development
+	StorageDriverFile   = "file"   // state is kept in files of config.Storage.Dir
+)
+
+// New creates the [Store] selected by application config.
+//
+//nolint:ireturn
+func New(conf config.Config) (Store, error) {
+	switch conf.Storage.Driver {
+	case StorageDriverMemory:
+		return NewMemory(), nil
+	case StorageDriverFile:
+		return NewFile(conf.Storage.Dir)
+	default:
+		return nil, fmt.Errorf("unknown storage driver '%s'", conf.Storage.Driver)
+	}
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const cloudTraceHeader = "X-Cloud-Trace-Context"
+
+// CloudTraceContext propagates trace context in the X-Cloud-Trace-Context header
+// sent by Google Cloud load balancers: TRACE_ID/SPAN_ID;o=OPTIONS, with a decimal span ID.
+type CloudTraceContext struct{}
+
+var _ propagation.TextMapPropagator = CloudTraceContext{}
+
+// Extract returns ctx with the remote span context in carrier's X-Cloud-Trace-Context header, if valid.
+func (CloudTraceContext) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	header := carrier.Get(cloudTraceHeader)
+	if header == "" {
+		return ctx
+	}
+
+	traceID, rest, _ := strings.Cut(header, "/")
+	spanID, options, _ := strings.Cut(rest, ";")
+
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return ctx
+	}
+	sid, err := strconv.ParseUint(spanID, 10, 64)
+	if err != nil || sid == 0 {
+		return ctx
+	}
+
+	var config trace.SpanContextConfig
+	config.TraceID = tid
+	config.SpanID = spanIDFromUint(sid)
+	config.Remote = true
+	if options == "o=1" {
+		config.TraceFlags = trace.FlagsSampled
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(config))
+}
+
+// Inject sets the X-Cloud-Trace-Context header from the span context in ctx, if valid.
+func (CloudTraceContext) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	sampled := 0
+	if sc.IsSampled() {
+		sampled = 1
+	}
+	sid := sc.SpanID()
+	var id uint64
+	for _, b := range sid {
+		id = id<<8 | uint64(b)
+	}
+
+	carrier.Set(cloudTraceHeader, fmt.Sprintf("%s/%d;o=%d", sc.TraceID(), id, sampled))
+}
+
+// Fields returns the header set by Inject.
+func (CloudTraceContext) Fields() []string {
+	return []string{cloudTraceHeader}
+}
+
+func spanIDFromUint(id uint64) trace.SpanID {
+	var sid trace.SpanID
+	for i := len(sid) - 1; i >= 0; i-- {
+		sid[i] = byte(id)
+		id >>= 8
+	}
+	return sid
+}
@@ -0,0 +1,90 @@
+// Package tracing traces requests with OpenTelemetry, propagating W3C and Google Cloud trace context.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/httputil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "ocomms"
+	tracerName  = "github.com/infotecho/ocomms"
+)
+
+// Setup installs the global trace context propagator and, if an OTLP endpoint is configured,
+// a tracer provider exporting spans to it. The returned function flushes pending spans.
+func Setup(ctx context.Context, conf config.Config) (func(context.Context) error, error) {
+	// traceparent is extracted last so that it wins over X-Cloud-Trace-Context when both are sent
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(CloudTraceContext{}, propagation.TraceContext{}))
+
+	if conf.Tracing.Endpoint == "" {
+		// spans are still created with the propagated trace and span IDs, so logs can be correlated
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(conf.Tracing.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	provider := NewProvider(conf, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider sampling according to config, e.g. with an in-memory exporter in tests.
+func NewProvider(conf config.Config, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.Tracing.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}, opts...)
+
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// Start starts a child span of the span in ctx, e.g. around a call to an external service.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...)) //nolint:spancheck
+}
+
+// Middleware starts a server span for each request served by h, continuing the caller's trace if any.
+// The span is named after the route pattern matched by an [http.ServeMux].
+func Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer(tracerName).Start(
+			ctx,
+			r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)),
+		)
+		defer span.End()
+
+		rec := httputil.NewStatusRecorder(w)
+		req := r.WithContext(ctx)
+		h.ServeHTTP(rec, req)
+
+		if req.Pattern != "" { // set by the mux while serving the request
+			span.SetName(req.Pattern)
+			span.SetAttributes(semconv.HTTPRoute(req.Pattern))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.Status))
+		if rec.Status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.Status))
+		}
+	})
+}
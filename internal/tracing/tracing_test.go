@@ -0,0 +1,100 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/infotecho/ocomms/internal/config"
+	"github.com/infotecho/ocomms/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentID = "00f067aa0ba902b7"
+)
+
+//nolint:paralleltest // sets the global tracer provider
+func TestMiddleware(t *testing.T) {
+	var conf config.Config
+	conf.Tracing.SampleRatio = 0 // sampled only because the caller's trace is
+
+	_, err := tracing.Setup(context.Background(), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(conf, sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /voice/{action}", func(_ http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "child")
+		span.End()
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/voice/inbound", nil)
+	req.Header.Set("Traceparent", "00-"+traceID+"-"+parentID+"-01")
+	req.Header.Set("X-Cloud-Trace-Context", "0af7651916cd43dd8448eb211c80319c/1;o=1")
+	tracing.Middleware(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Got %d spans, want a child span and a server span", len(spans))
+	}
+	child, server := spans[0], spans[1]
+
+	if server.Name != "POST /voice/{action}" {
+		t.Errorf("Server span name = %q, want the route pattern", server.Name)
+	}
+	if got := server.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("Server span trace ID = %s, want the traceparent's %s", got, traceID)
+	}
+	if got := server.Parent.SpanID().String(); got != parentID {
+		t.Errorf("Server span parent = %s, want %s", got, parentID)
+	}
+	if child.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("Child span parent = %s, want the server span %s", child.Parent.SpanID(), server.SpanContext.SpanID())
+	}
+}
+
+func TestCloudTraceContext(t *testing.T) {
+	t.Parallel()
+
+	header := "105445aa7843bc8bf206b12000100000/1;o=1"
+	carrier := propagation.HeaderCarrier{}
+	carrier.Set("X-Cloud-Trace-Context", header)
+
+	ctx := tracing.CloudTraceContext{}.Extract(context.Background(), carrier)
+	sc := trace.SpanContextFromContext(ctx)
+	if got := sc.TraceID().String(); got != "105445aa7843bc8bf206b12000100000" {
+		t.Errorf("Trace ID = %s", got)
+	}
+	if got := sc.SpanID().String(); got != "0000000000000001" {
+		t.Errorf("Span ID = %s", got)
+	}
+	if !sc.IsSampled() || !sc.IsRemote() {
+		t.Errorf("Span context should be remote and sampled: %+v", sc)
+	}
+
+	injected := propagation.HeaderCarrier{}
+	tracing.CloudTraceContext{}.Inject(ctx, injected)
+	if got := injected.Get("X-Cloud-Trace-Context"); got != header {
+		t.Errorf("Injected header = %q, want %q", got, header)
+	}
+
+	for _, invalid := range []string{"", "not-a-trace/1;o=1", "105445aa7843bc8bf206b12000100000/abc;o=1"} {
+		carrier := propagation.HeaderCarrier{}
+		carrier.Set("X-Cloud-Trace-Context", invalid)
+		ctx := tracing.CloudTraceContext{}.Extract(context.Background(), carrier)
+		if trace.SpanContextFromContext(ctx).IsValid() {
+			t.Errorf("Header %q should not be extracted", invalid)
+		}
+	}
+}
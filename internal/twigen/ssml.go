@@ -0,0 +1,55 @@
+package twigen
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/infotecho/ocomms/internal/i18n"
+	"github.com/twilio/twilio-go/twiml"
+)
+
+const ssmlTextTag = "ocomms-text"
+
+// ssml is an SSML tag or text in a <Say> verb.
+// twiml's SSML elements don't render SSML tag and attribute names (e.g. <SayAs interpretAs="">),
+// and twiml only supports text before an element's children, so text is rendered as a placeholder element
+// which [renderSSML] replaces with its text.
+type ssml i18n.SSML
+
+func (s ssml) GetName() string {
+	if s.Tag == "" {
+		return ssmlTextTag
+	}
+	return s.Tag
+}
+
+func (s ssml) GetText() string                                 { return s.Text }
+func (s ssml) GetAttr() (map[string]string, map[string]string) { return s.Attrs, nil }
+func (s ssml) GetInnerElements() []twiml.Element               { return nil }
+
+// ssmlElements converts the parts of an i18n message into children of a <Say> verb.
+func ssmlElements(parts []i18n.SSML) []twiml.Element {
+	elements := make([]twiml.Element, 0, len(parts))
+	for _, part := range parts {
+		elements = append(elements, ssml(part))
+	}
+	return elements
+}
+
+// renderSSML replaces the placeholders of SSML text under el with their text,
+// and sorts the attributes of SSML tags, which twiml adds in random order.
+func renderSSML(el *etree.Element) {
+	for _, child := range el.ChildElements() {
+		switch {
+		case child.Tag == ssmlTextTag:
+			index := child.Index()
+			el.RemoveChildAt(index)
+			el.InsertChildAt(index, etree.NewText(child.Text()))
+		case el.Tag == "Say":
+			slices.SortFunc(child.Attr, func(a, b etree.Attr) int { return strings.Compare(a.Key, b.Key) })
+		default:
+			renderSSML(child)
+		}
+	}
+}
@@ -5,29 +5,62 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/infotecho/ocomms/internal/audio"
 	"github.com/infotecho/ocomms/internal/config"
 	"github.com/infotecho/ocomms/internal/i18n"
+	"github.com/infotecho/ocomms/internal/metrics"
+	"github.com/infotecho/ocomms/internal/tracing"
+	"github.com/infotecho/ocomms/internal/voicemail"
 	"github.com/twilio/twilio-go/twiml"
 )
 
 // Voice generates TwiML for Programmable Voice.
 type Voice struct {
-	Config config.Config
-	Logger *slog.Logger
-	I18n   *i18n.MessageProvider
+	Audio   *audio.Library
+	Config  config.Config
+	Logger  *slog.Logger
+	I18n    *i18n.MessageProvider
+	Metrics *metrics.Metrics
 }
 
 func (v Voice) voice(ctx context.Context, verbs []twiml.Element) string {
-	res, err := twiml.Voice(verbs)
+	ctx, span := tracing.Start(ctx, "twigen.voice")
+	defer span.End()
+
+	doc, response := twiml.CreateDocument()
+	twiml.AddAllVerbs(response, verbs)
+	renderSSML(response)
+
+	res, err := twiml.ToXML(doc)
 	if err != nil {
 		v.Logger.ErrorContext(ctx, "Error generating TwiML response", "err", err)
 	}
 	return res
 }
 
-func (v Voice) say(ctx context.Context, lang string, getter func(m i18n.Messages) string) *twiml.VoiceSay {
+// WithQuery adds query to the query string of an action URL.
+func WithQuery(action string, query url.Values) string {
+	switch {
+	case len(query) == 0:
+		return action
+	case strings.Contains(action, "?"):
+		return action + "&" + query.Encode()
+	default:
+		return action + "?" + query.Encode()
+	}
+}
+
+// withLang adds the caller's language to the query string of an action URL.
+func withLang(action string, lang string) string {
+	return WithQuery(action, url.Values{"lang": []string{lang}})
+}
+
+func (v Voice) say(ctx context.Context, lang string, getter func(m i18n.Messages) string) twiml.Element {
 	return v.sayTemplate(ctx, lang, getter, map[string]string{})
 }
 
@@ -36,18 +69,78 @@ func (v Voice) sayTemplate(
 	lang string,
 	getter func(m i18n.Messages) string,
 	replacements map[string]string,
-) *twiml.VoiceSay {
-	msg := v.I18n.MessageReplace(ctx, lang, getter, replacements)
+) twiml.Element {
+	asset, msg := i18n.Audio(v.I18n.Template(ctx, lang, getter))
+	if asset != "" {
+		if url, ok := v.Audio.URL(lang, asset); ok {
+			return &twiml.VoicePlay{Url: url}
+		}
+		v.Logger.WarnContext(ctx, "No audio prompt found, falling back to text-to-speech", "lang", lang, "asset", asset)
+		v.Metrics.I18nFallback(metrics.FallbackAudio, lang)
+	}
 
 	voiceLang, ok := v.Config.Twilio.Languages[lang]
 	if !ok {
 		v.Logger.ErrorContext(ctx, fmt.Sprintf("No corresponding Twilio language found for language code '%s'", lang))
 	}
 
-	return &twiml.VoiceSay{
-		Language: voiceLang,
-		Message:  msg,
+	say := &twiml.VoiceSay{
+		Language: voiceLang.Code,
+		Voice:    voiceLang.Voice,
+	}
+
+	// replacements go into the parsed text, so that they are spoken as is rather than parsed as SSML
+	parts, err := i18n.ParseSSML(msg)
+	if err != nil {
+		// messages are validated when loaded, so this is unexpected
+		v.Logger.ErrorContext(ctx, "Error parsing SSML in i18n message", "err", err)
+		say.Message = v.I18n.Replace(ctx, msg, replacements)
+		return say
+	}
+	for i := range parts {
+		parts[i].Text = v.I18n.Replace(ctx, parts[i].Text, replacements)
+	}
+	if len(parts) == 1 && parts[0].Tag == "" {
+		say.Message = parts[0].Text
+	} else {
+		say.InnerElements = ssmlElements(parts)
+	}
+	return say
+}
+
+// sayOption generates a prompt for the caller to press digit to select a menu option,
+// or to say the option's first keyword if speech input is enabled for the menu.
+func (v Voice) sayOption(
+	ctx context.Context,
+	lang string,
+	digit string,
+	speech bool,
+	getter func(m i18n.Messages) string,
+	speechGetter func(m i18n.Messages) string,
+	keywordsGetter func(m i18n.Messages) string,
+) twiml.Element {
+	if !speech {
+		return v.sayTemplate(ctx, lang, getter, map[string]string{"digit": digit})
+	}
+
+	keyword := ""
+	if keywords := v.keywords(ctx, lang, keywordsGetter); len(keywords) > 0 {
+		keyword = keywords[0]
 	}
+
+	return v.sayTemplate(ctx, lang, speechGetter, map[string]string{"digit": digit, "keyword": keyword})
+}
+
+func (v Voice) keywords(ctx context.Context, lang string, getter func(m i18n.Messages) string) []string {
+	return i18n.Keywords(v.I18n.Message(ctx, lang, getter))
+}
+
+// acceptSpeech configures gather to accept speech recognized in lang as well as key presses.
+func (v Voice) acceptSpeech(gather *twiml.VoiceGather, lang string, hints []string) {
+	gather.Input = "dtmf speech"
+	gather.Language = v.Config.Twilio.Languages[lang].Code
+	gather.Hints = strings.Join(hints, ", ")
+	gather.SpeechTimeout = "auto"
 }
 
 // Noop generates an empty TwiML responds that instructs Twilio to do nothing.
@@ -55,54 +148,391 @@ func (v Voice) Noop(ctx context.Context) string {
 	return v.voice(ctx, []twiml.Element{})
 }
 
-// GatherOutboundNumber generates TwiML gather a phone number to place an outbound call.
-func (v Voice) GatherOutboundNumber(ctx context.Context, actionDialOut string) string {
-	say := &twiml.VoiceSay{
-		Language: "en-US",
-		Message:  "Enter the number you wish to call, then press pound.",
+// Reject generates TwiML to reject a call without answering it.
+func (v Voice) Reject(ctx context.Context) string {
+	return v.voice(ctx, []twiml.Element{&twiml.VoiceReject{Reason: "rejected"}})
+}
+
+// Hangup generates TwiML to end a call.
+func (v Voice) Hangup(ctx context.Context) string {
+	return v.voice(ctx, []twiml.Element{&twiml.VoiceHangup{}})
+}
+
+// GatherScreenChallenge generates TwiML challenging a caller who failed screening to press a key to continue,
+// in each language of the profile, as the caller hasn't chosen one yet.
+func (v Voice) GatherScreenChallenge(
+	ctx context.Context,
+	actionScreen string,
+	profile config.Profile,
+	continueKey string,
+) string {
+	says := make([]twiml.Element, len(profile.Languages))
+	for i, lang := range profile.Languages {
+		says[i] = v.sayTemplate(ctx, lang,
+			func(m i18n.Messages) string { return m.Voice.ScreenChallenge },
+			map[string]string{"digit": continueKey},
+		)
 	}
+
 	gather := &twiml.VoiceGather{
-		Action:        actionDialOut,
+		Action:              actionScreen,
+		ActionOnEmptyResult: "true",
+		NumDigits:           "1",
+		Timeout:             strconv.Itoa(v.Config.Twilio.Timeouts.GatherScreening),
+		InnerElements:       says,
+	}
+	return v.voice(ctx, []twiml.Element{gather})
+}
+
+// GatherAgentPIN generates TwiML to have an agent enter their PIN before placing an outbound call.
+func (v Voice) GatherAgentPIN(ctx context.Context, actionVerifyPIN string, lang string) string {
+	say := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.AgentPIN })
+	gather := &twiml.VoiceGather{
+		Action:        actionVerifyPIN,
 		InnerElements: []twiml.Element{say},
+		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherAgentPIN),
+	}
+	return v.voice(ctx, []twiml.Element{gather})
+}
+
+// RejectPIN generates TwiML to end a call after an agent entered an incorrect PIN.
+// If locked is true, the agent is told their number is locked out after too many incorrect PINs instead.
+func (v Voice) RejectPIN(ctx context.Context, lang string, locked bool) string {
+	say := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.PINInvalid })
+	if locked {
+		say = v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.PINLocked })
+	}
+	return v.voice(ctx, []twiml.Element{say, &twiml.VoiceHangup{}})
+}
+
+// Apologize generates TwiML to apologize to a caller for a technical error,
+// then to redirect them to leave a voicemail, unless actionStartVoicemail is empty.
+func (v Voice) Apologize(ctx context.Context, actionStartVoicemail string, lang string) string {
+	if lang == "" {
+		lang = v.Config.I18N.DefaultLang
+	}
+	say := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.TechnicalError })
+	if actionStartVoicemail == "" {
+		return v.voice(ctx, []twiml.Element{say, &twiml.VoiceHangup{}})
+	}
+
+	redirect := &twiml.VoiceRedirect{
+		Url: withLang(actionStartVoicemail, lang),
+	}
+	return v.voice(ctx, []twiml.Element{say, redirect})
+}
+
+// GatherOutboundNumber generates TwiML gather a phone number to place an outbound call.
+// If rejected is true, the agent is first told that the previously entered number can't be dialed.
+func (v Voice) GatherOutboundNumber(ctx context.Context, actionDialOut string, lang string, rejected bool) string {
+	say := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.OutboundNumber })
+	sayMenuHint := v.say(ctx, lang, func(m i18n.Messages) string {
+		return m.Voice.Presence.MenuHint
+	})
+	sayVoicemailsHint := v.say(ctx, lang, func(m i18n.Messages) string {
+		return m.Voice.Voicemails.MenuHint
+	})
+	gather := &twiml.VoiceGather{
+		Action:        actionDialOut,
+		InnerElements: []twiml.Element{say, sayMenuHint, sayVoicemailsHint},
 		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherOutboundNumber),
 	}
+
+	if rejected {
+		sayRejected := v.say(ctx, lang, func(m i18n.Messages) string {
+			return m.Voice.OutboundRejected
+		})
+		return v.voice(ctx, []twiml.Element{sayRejected, gather})
+	}
 	return v.voice(ctx, []twiml.Element{gather})
 }
 
+// GatherPresence generates TwiML for an agent to change their availability to take calls.
+func (v Voice) GatherPresence(ctx context.Context, actionSetPresence string, lang string, status string) string {
+	sayCurrent := v.sayTemplate(ctx, lang,
+		func(m i18n.Messages) string { return m.Voice.Presence.Current },
+		map[string]string{
+			"status": v.I18n.Message(ctx, lang, func(m i18n.Messages) string { return m.PresenceStatus(status) }),
+		},
+	)
+	sayMenu := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Presence.Menu })
+
+	gather := &twiml.VoiceGather{
+		Action:        actionSetPresence,
+		InnerElements: []twiml.Element{sayCurrent, sayMenu},
+		NumDigits:     "1",
+		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherAgentMenu),
+	}
+	return v.voice(ctx, []twiml.Element{gather})
+}
+
+// SayPresenceUpdated generates TwiML to confirm to an agent that their availability was changed.
+func (v Voice) SayPresenceUpdated(ctx context.Context, lang string, status string) string {
+	say := v.sayTemplate(ctx, lang,
+		func(m i18n.Messages) string { return m.Voice.Presence.Updated },
+		map[string]string{
+			"status": v.I18n.Message(ctx, lang, func(m i18n.Messages) string { return m.PresenceStatus(status) }),
+		},
+	)
+	return v.voice(ctx, []twiml.Element{say, &twiml.VoiceHangup{}})
+}
+
+// PlayVoicemail generates TwiML playing a voicemail to an agent, followed by the voicemail menu.
+// If unheard is positive, the agent is first told how many voicemails they have.
+// Any prompts, e.g. confirming what happened to the previous voicemail, are said first.
+func (v Voice) PlayVoicemail(
+	ctx context.Context,
+	actionVoicemails string,
+	lang string,
+	vm voicemail.Voicemail,
+	unheard int,
+	prompts ...func(m i18n.Messages) string,
+) string {
+	verbs := make([]twiml.Element, 0, len(prompts)+4) //nolint:mnd
+	for _, prompt := range prompts {
+		verbs = append(verbs, v.say(ctx, lang, prompt))
+	}
+	switch {
+	case unheard == 1:
+		verbs = append(verbs, v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Voicemails.CountOne }))
+	case unheard > 1:
+		verbs = append(verbs, v.sayTemplate(ctx, lang,
+			func(m i18n.Messages) string { return m.Voice.Voicemails.Count },
+			map[string]string{"count": strconv.Itoa(unheard)},
+		))
+	}
+
+	sayIntro := v.sayTemplate(ctx, lang,
+		func(m i18n.Messages) string { return m.Voice.Voicemails.Intro },
+		map[string]string{"phoneNumber": vm.From, "time": v.I18n.FormatTime(vm.Time)},
+	)
+	play := &twiml.VoicePlay{Url: vm.RecordingURL}
+	sayMenu := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Voicemails.Menu })
+	gather := &twiml.VoiceGather{
+		Action:              actionVoicemails + "?voicemail=" + url.QueryEscape(vm.RecordingSid),
+		ActionOnEmptyResult: "true", // move on to the next voicemail, e.g. for agents listening hands-free
+		InnerElements:       []twiml.Element{sayMenu},
+		NumDigits:           "1",
+		Timeout:             strconv.Itoa(v.Config.Twilio.Timeouts.GatherAgentMenu),
+	}
+
+	return v.voice(ctx, append(verbs, sayIntro, play, gather))
+}
+
+// SayNoVoicemails generates TwiML to tell an agent there are no more voicemails to listen to, and hang up.
+// If none is true, the agent hadn't heard any voicemail.
+// Any prompts, e.g. confirming what happened to the previous voicemail, are said first.
+func (v Voice) SayNoVoicemails(
+	ctx context.Context,
+	lang string,
+	none bool,
+	prompts ...func(m i18n.Messages) string,
+) string {
+	verbs := make([]twiml.Element, 0, len(prompts)+2) //nolint:mnd
+	for _, prompt := range prompts {
+		verbs = append(verbs, v.say(ctx, lang, prompt))
+	}
+	if none {
+		verbs = append(verbs, v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Voicemails.None }))
+	} else {
+		verbs = append(verbs, v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Voicemails.End }))
+	}
+
+	return v.voice(ctx, append(verbs, &twiml.VoiceHangup{}))
+}
+
 // DialOut generates TwiML to dial out as the company.
 func (v Voice) DialOut(ctx context.Context, number string) string {
+	return v.voice(ctx, []twiml.Element{v.dialOut(number, "")})
+}
+
+// GatherBridgeCallback generates TwiML asking an agent who answered a call back to press acceptKey to call number,
+// so that the client isn't called by the agent's personal voicemail.
+func (v Voice) GatherBridgeCallback(
+	ctx context.Context,
+	actionBridgeCallback string,
+	acceptKey string,
+	number string,
+	lang string,
+) string {
+	sayAccept := v.sayTemplate(ctx, lang,
+		func(m i18n.Messages) string { return m.Voice.AcceptCallBack },
+		map[string]string{"phoneNumber": number, "digit": acceptKey},
+	)
+	gather := &twiml.VoiceGather{
+		Action:        withLang(actionBridgeCallback, lang),
+		NumDigits:     "1",
+		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherAcceptCall),
+		InnerElements: []twiml.Element{sayAccept},
+	}
+	return v.voice(ctx, []twiml.Element{gather, &twiml.VoiceHangup{}})
+}
+
+// BridgeCallback generates TwiML connecting an agent who accepted a call back to the client, from companyDID.
+func (v Voice) BridgeCallback(ctx context.Context, number string, companyDID string, lang string) string {
+	say := v.sayTemplate(ctx, lang,
+		func(m i18n.Messages) string { return m.Voice.CallingBack },
+		map[string]string{"phoneNumber": number},
+	)
+	return v.voice(ctx, []twiml.Element{say, v.dialOut(number, companyDID)})
+}
+
+func (v Voice) dialOut(number string, callerID string) *twiml.VoiceDial {
 	dial := &twiml.VoiceDial{
-		Number: number,
+		CallerId: callerID,
+		Number:   number,
 	}
 	if v.Config.Twilio.RecordOutboundCalls {
 		dial.Record = "record-from-answer"
 	}
-	return v.voice(ctx, []twiml.Element{dial})
+	return dial
 }
 
-// GatherLanguage generates TwiML to gather a caller's language preference.
-func (v Voice) GatherLanguage(ctx context.Context, actionConnectAgent string, intro bool) string {
-	sayWelcome := v.say(ctx, "en", func(m i18n.Messages) string { return m.Voice.Welcome })
-	sayEn := v.sayTemplate(ctx, "en",
-		func(m i18n.Messages) string { return m.Voice.LangSelect },
-		map[string]string{"digit": "1"},
-	)
-	sayFr := v.sayTemplate(ctx, "fr",
-		func(m i18n.Messages) string { return m.Voice.LangSelect },
-		map[string]string{"digit": "2"},
+// GatherCallbackNumber generates TwiML asking a caller scheduling a call back to confirm number,
+// or to enter the number to call back if number is empty. If rejected is true, the number entered can't be called.
+func (v Voice) GatherCallbackNumber(
+	ctx context.Context,
+	actionCallbackNumber string,
+	lang string,
+	number string,
+	rejected bool,
+) string {
+	var prompts []twiml.Element
+	if rejected {
+		prompts = append(prompts, v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Callbacks.InvalidNumber }))
+	}
+	if number != "" {
+		prompts = append(prompts, v.sayTemplate(ctx, lang,
+			func(m i18n.Messages) string { return m.Voice.Callbacks.ConfirmNumber },
+			map[string]string{"phoneNumber": number},
+		))
+	} else {
+		prompts = append(prompts, v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Callbacks.EnterNumber }))
+	}
+
+	gather := &twiml.VoiceGather{
+		Action:        withLang(actionCallbackNumber, lang),
+		FinishOnKey:   "#",
+		InnerElements: prompts,
+		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherCallback),
+	}
+
+	return v.voice(ctx, []twiml.Element{gather})
+}
+
+// GatherCallbackSlot generates TwiML asking a caller to choose one of slots to be called back at number.
+// Each slot is selected by its position, starting at 1.
+func (v Voice) GatherCallbackSlot(
+	ctx context.Context,
+	actionCallbackSlot string,
+	lang string,
+	number string,
+	slots []time.Time,
+) string {
+	prompts := make([]twiml.Element, len(slots))
+	unix := make([]string, len(slots))
+	for i, slot := range slots {
+		replacements := v.slotReplacements(ctx, lang, slot)
+		replacements["digit"] = strconv.Itoa(i + 1)
+		prompts[i] = v.sayTemplate(ctx, lang, func(m i18n.Messages) string { return m.Voice.Callbacks.Slot }, replacements)
+		unix[i] = strconv.FormatInt(slot.Unix(), 10)
+	}
+
+	// the slots offered are carried to the action, since time passes before the caller chooses
+	query := url.Values{}
+	query.Set("number", number)
+	query.Set("slots", strings.Join(unix, ","))
+
+	gather := &twiml.VoiceGather{
+		Action:        withLang(actionCallbackSlot+"?"+query.Encode(), lang),
+		InnerElements: prompts,
+		NumDigits:     "1",
+		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherCallback),
+	}
+
+	return v.voice(ctx, []twiml.Element{gather})
+}
+
+// SayCallbackScheduled generates TwiML to confirm the slot of a scheduled call back, and hang up.
+func (v Voice) SayCallbackScheduled(ctx context.Context, lang string, slot time.Time) string {
+	say := v.sayTemplate(ctx, lang,
+		func(m i18n.Messages) string { return m.Voice.Callbacks.Scheduled },
+		v.slotReplacements(ctx, lang, slot),
 	)
+	return v.voice(ctx, []twiml.Element{say, &twiml.VoiceHangup{}})
+}
+
+// slotReplacements returns the spoken day and time of a call back slot, e.g. "today" and "3:30 PM".
+func (v Voice) slotReplacements(ctx context.Context, lang string, slot time.Time) map[string]string {
+	slot = v.I18n.Local(slot)
+	now := v.I18n.Local(time.Now())
+
+	var day func(m i18n.Messages) string
+	switch {
+	case sameDay(slot, now):
+		day = func(m i18n.Messages) string { return m.Voice.Callbacks.Today }
+	case sameDay(slot, now.AddDate(0, 0, 1)):
+		day = func(m i18n.Messages) string { return m.Voice.Callbacks.Tomorrow }
+	default:
+		weekday := strings.ToLower(slot.Weekday().String()[:3])
+		day = func(m i18n.Messages) string { return m.Voice.Callbacks.Days[weekday] }
+	}
+
+	layout := v.I18n.Message(ctx, lang, func(m i18n.Messages) string { return m.Voice.Callbacks.TimeFormat })
+	return map[string]string{
+		"day":  v.I18n.Message(ctx, lang, day),
+		"time": slot.Format(layout),
+	}
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	return a.YearDay() == b.YearDay() && a.Year() == b.Year()
+}
+
+// GatherLanguage generates TwiML to gather a caller's language preference.
+func (v Voice) GatherLanguage(
+	ctx context.Context,
+	actionConnectAgent string,
+	profile config.Profile,
+	intro bool,
+) string {
+	speech := v.Config.Twilio.Speech.Menus.Language
+	langKeywords := func(m i18n.Messages) string { return m.Voice.Speech.Keywords.Lang }
+
+	sayWelcome := v.say(ctx, profile.Languages[0], func(m i18n.Messages) string { return m.Greeting(profile.ID) })
+	sayLangs := make([]twiml.Element, len(profile.Languages))
+	var hints []string
+	for i, lang := range profile.Languages {
+		sayLangs[i] = v.sayOption(ctx, lang, strconv.Itoa(i+1), speech,
+			func(m i18n.Messages) string { return m.Voice.LangSelect },
+			func(m i18n.Messages) string { return m.Voice.Speech.LangSelect },
+			langKeywords,
+		)
+		hints = append(hints, v.keywords(ctx, lang, langKeywords)...)
+	}
+
+	if speech {
+		// a gather recognizes speech in one language, so each option is gathered in its own language
+		menu := v.speechLanguageGathers(actionConnectAgent, profile, sayLangs, hints)
+		if intro {
+			welcome := v.speechLanguageGathers(actionConnectAgent, profile, sayLangs, hints)
+			welcome[0].InnerElements = append([]twiml.Element{sayWelcome}, welcome[0].InnerElements...)
+			return v.voice(ctx, append(gatherElements(welcome), gatherElements(menu)...))
+		}
+		return v.voice(ctx, append(gatherElements(menu), gatherElements(menu)...))
+	}
 
 	gatherWelcome := &twiml.VoiceGather{
 		Action:        actionConnectAgent,
 		NumDigits:     "1",
 		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherLanguage),
-		InnerElements: []twiml.Element{sayWelcome, sayEn, sayFr},
+		InnerElements: append([]twiml.Element{sayWelcome}, sayLangs...),
 	}
 	gather := &twiml.VoiceGather{
 		Action:        actionConnectAgent,
 		NumDigits:     "1",
 		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherLanguage),
-		InnerElements: []twiml.Element{sayEn, sayFr},
+		InnerElements: sayLangs,
 	}
 
 	if intro {
@@ -111,6 +541,111 @@ func (v Voice) GatherLanguage(ctx context.Context, actionConnectAgent string, in
 	return v.voice(ctx, []twiml.Element{gather, gather})
 }
 
+// speechLanguageGathers returns a gather for each option of the language menu, recognizing speech in its language.
+// Callers get a short pause to answer after each option, and the full timeout after the last.
+func (v Voice) speechLanguageGathers(
+	actionConnectAgent string,
+	profile config.Profile,
+	sayLangs []twiml.Element,
+	hints []string,
+) []*twiml.VoiceGather {
+	gathers := make([]*twiml.VoiceGather, len(profile.Languages))
+	for i, lang := range profile.Languages {
+		timeout := v.Config.Twilio.Timeouts.GatherLanguageSpeech
+		if i == len(profile.Languages)-1 {
+			timeout = v.Config.Twilio.Timeouts.GatherLanguage
+		}
+		gathers[i] = &twiml.VoiceGather{
+			Action:        actionConnectAgent,
+			NumDigits:     "1",
+			Timeout:       strconv.Itoa(timeout),
+			InnerElements: []twiml.Element{sayLangs[i]},
+		}
+		v.acceptSpeech(gathers[i], lang, hints)
+	}
+	return gathers
+}
+
+func gatherElements(gathers []*twiml.VoiceGather) []twiml.Element {
+	elements := make([]twiml.Element, len(gathers))
+	for i, gather := range gathers {
+		elements[i] = gather
+	}
+	return elements
+}
+
+// Welcome generates TwiML to greet a caller in the only language offered by a profile,
+// before connecting them to an agent.
+func (v Voice) Welcome(ctx context.Context, actionConnectAgent string, profile config.Profile, lang string) string {
+	sayWelcome := v.say(ctx, lang, func(m i18n.Messages) string { return m.Greeting(profile.ID) })
+	redirect := &twiml.VoiceRedirect{
+		Url: actionConnectAgent + "?lang=" + lang,
+	}
+
+	return v.voice(ctx, []twiml.Element{sayWelcome, redirect})
+}
+
+// GreetReturningCaller generates TwiML to welcome back a caller in their remembered language,
+// giving them a chance to press a key to change it before being connected to an agent.
+func (v Voice) GreetReturningCaller(
+	ctx context.Context,
+	actionConnectAgent string,
+	changeLangKey string,
+	lang string,
+) string {
+	speech := v.Config.Twilio.Speech.Menus.Language
+	langChangeKeywords := func(m i18n.Messages) string { return m.Voice.Speech.Keywords.LangChange }
+
+	sayWelcome := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.WelcomeBack })
+	sayChange := v.sayOption(ctx, lang, changeLangKey, speech,
+		func(m i18n.Messages) string { return m.Voice.LangChange },
+		func(m i18n.Messages) string { return m.Voice.Speech.LangChange },
+		langChangeKeywords,
+	)
+
+	gather := &twiml.VoiceGather{
+		Action:        actionConnectAgent + "?lang=" + lang,
+		NumDigits:     "1",
+		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherLanguageChange),
+		InnerElements: []twiml.Element{sayWelcome, sayChange},
+	}
+	if speech {
+		v.acceptSpeech(gather, lang, v.keywords(ctx, lang, langChangeKeywords))
+	}
+	// reached if the caller doesn't press a key
+	redirect := &twiml.VoiceRedirect{
+		Url: actionConnectAgent + "?lang=" + lang,
+	}
+
+	return v.voice(ctx, []twiml.Element{gather, redirect})
+}
+
+// GatherMenu generates TwiML for callers to select the skill group of agents they need from menu options.
+func (v Voice) GatherMenu(ctx context.Context, actionRoute string, lang string, options []config.MenuOption) string {
+	says := make([]twiml.Element, 0, len(options))
+	for _, option := range options {
+		prompt := func(m i18n.Messages) string { return m.Voice.Menu[option.Skill] }
+		if v.I18n.Message(ctx, lang, prompt) == "" {
+			v.Logger.ErrorContext(ctx, "No menu prompt found for skill", "skill", option.Skill, "lang", lang)
+			continue
+		}
+		says = append(says, v.sayTemplate(ctx, lang, prompt, map[string]string{"digit": option.Digit}))
+	}
+
+	gather := &twiml.VoiceGather{
+		Action:        actionRoute + "?lang=" + lang,
+		NumDigits:     "1",
+		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherMenu),
+		InnerElements: says,
+	}
+	// reached if the caller doesn't press a key
+	redirect := &twiml.VoiceRedirect{
+		Url: actionRoute + "?lang=" + lang,
+	}
+
+	return v.voice(ctx, []twiml.Element{gather, redirect})
+}
+
 // DialAgent generates TwiML to connect a caller to an agent.
 func (v Voice) DialAgent(
 	ctx context.Context,
@@ -118,10 +653,10 @@ func (v Voice) DialAgent(
 	actionEndCall string,
 	callerID string,
 	lang string,
+	agentDIDs []string,
 ) string {
 	sayHold := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.PleaseHold })
 
-	agentDIDs := v.Config.Twilio.AgentDIDs
 	numbers := make([]twiml.Element, len(agentDIDs))
 	for i, agentDID := range agentDIDs {
 		numbers[i] = &twiml.VoiceNumber{
@@ -131,7 +666,7 @@ func (v Voice) DialAgent(
 	}
 
 	dialAgents := &twiml.VoiceDial{
-		Action:        actionEndCall + "?lang=" + lang,
+		Action:        withLang(actionEndCall, lang),
 		CallerId:      callerID,
 		InnerElements: numbers,
 		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.DialAgents),
@@ -143,12 +678,86 @@ func (v Voice) DialAgent(
 	return v.voice(ctx, []twiml.Element{sayHold, dialAgents})
 }
 
+// WaitInConference generates TwiML for a caller to wait in a conference until an agent joins.
+func (v Voice) WaitInConference(ctx context.Context, actionEndConference string, room string, lang string) string {
+	sayHold := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.PleaseHold })
+
+	conference := &twiml.VoiceConference{
+		Name:                   room,
+		Beep:                   "false",
+		StartConferenceOnEnter: "false",
+		EndConferenceOnExit:    "true",
+	}
+	if v.Config.Twilio.RecordInboundCalls {
+		conference.Record = "record-from-start"
+	}
+	dial := &twiml.VoiceDial{
+		Action:        withLang(actionEndConference, lang),
+		InnerElements: []twiml.Element{conference},
+	}
+
+	return v.voice(ctx, []twiml.Element{sayHold, dial})
+}
+
+// JoinConference generates TwiML for an agent to join the conference where a caller waits,
+// after saying prompts. Agents can press star to leave the conference for the transfer menu.
+func (v Voice) JoinConference(
+	ctx context.Context,
+	actionTransferMenu string,
+	room string,
+	lang string,
+	prompts ...func(m i18n.Messages) string,
+) string {
+	verbs := make([]twiml.Element, 0, len(prompts)+1)
+	for _, prompt := range prompts {
+		verbs = append(verbs, v.say(ctx, lang, prompt))
+	}
+
+	conference := &twiml.VoiceConference{
+		Name:                   room,
+		Beep:                   "false",
+		StartConferenceOnEnter: "true",
+		EndConferenceOnExit:    "false",
+	}
+	dial := &twiml.VoiceDial{
+		Action:        withLang(actionTransferMenu, lang),
+		HangupOnStar:  "true",
+		InnerElements: []twiml.Element{conference},
+	}
+
+	return v.voice(ctx, append(verbs, dial))
+}
+
+// GatherTransfer generates TwiML for an agent to transfer a call, add another agent, or return to the call.
+func (v Voice) GatherTransfer(ctx context.Context, actionTransfer string, lang string) string {
+	sayMenu := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Transfer.Menu })
+	gather := &twiml.VoiceGather{
+		Action:        withLang(actionTransfer, lang),
+		NumDigits:     "1",
+		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherTransfer),
+		InnerElements: []twiml.Element{sayMenu},
+	}
+	// reached if the agent doesn't press a key, to return to the call
+	redirect := &twiml.VoiceRedirect{
+		Url: withLang(actionTransfer, lang),
+	}
+
+	return v.voice(ctx, []twiml.Element{gather, redirect})
+}
+
+// SayTransferred generates TwiML to hang up on an agent after they transferred a call.
+func (v Voice) SayTransferred(ctx context.Context, lang string) string {
+	say := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Transfer.Transferring })
+	hangup := &twiml.VoiceHangup{}
+	return v.voice(ctx, []twiml.Element{say, hangup})
+}
+
 // GatherAccept generates TwiML to have an agent confirm acceptance of a call.
 func (v Voice) GatherAccept(ctx context.Context, actionConfirmConnected string, lang string) string {
 	sayAccept := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.AcceptCall })
 	hangup := &twiml.VoiceHangup{}
 	gather := &twiml.VoiceGather{
-		Action:        actionConfirmConnected + "?lang=" + lang,
+		Action:        withLang(actionConfirmConnected, lang),
 		NumDigits:     "1",
 		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherAcceptCall),
 		InnerElements: []twiml.Element{sayAccept},
@@ -167,22 +776,66 @@ func (v Voice) GatherVoicemailStart(
 	ctx context.Context,
 	actionStartVoicemail string,
 	recordKey string,
+	callbackKey string,
+	lang string,
+) string {
+	return v.gatherVoicemailStart(ctx, actionStartVoicemail, recordKey, callbackKey, lang)
+}
+
+// GatherVoicemailClosed generates TwiML to tell callers that the office is closed,
+// and instruct them to leave a voicemail.
+func (v Voice) GatherVoicemailClosed(
+	ctx context.Context,
+	actionStartVoicemail string,
+	recordKey string,
+	callbackKey string,
 	lang string,
 ) string {
-	say1 := v.sayTemplate(ctx, lang,
+	sayClosed := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Closed })
+	return v.gatherVoicemailStart(ctx, actionStartVoicemail, recordKey, callbackKey, lang, sayClosed)
+}
+
+// GatherVoicemailNoSlots generates TwiML to tell callers that no call back can be scheduled,
+// and instruct them to leave a voicemail instead.
+func (v Voice) GatherVoicemailNoSlots(
+	ctx context.Context,
+	actionStartVoicemail string,
+	recordKey string,
+	lang string,
+) string {
+	sayNoSlots := v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.Callbacks.NoSlots })
+	return v.gatherVoicemailStart(ctx, actionStartVoicemail, recordKey, "", lang, sayNoSlots)
+}
+
+// gatherVoicemailStart invites callers to leave a voicemail,
+// or to schedule a call back if callbackKey isn't empty and scheduled call backs are enabled.
+func (v Voice) gatherVoicemailStart(
+	ctx context.Context,
+	actionStartVoicemail string,
+	recordKey string,
+	callbackKey string,
+	lang string,
+	intro ...twiml.Element,
+) string {
+	speech := v.Config.Twilio.Speech.Menus.Voicemail
+	voicemailKeywords := func(m i18n.Messages) string { return m.Voice.Speech.Keywords.Voicemail }
+
+	say1 := v.sayOption(ctx, lang, recordKey, speech,
 		func(m i18n.Messages) string { return m.Voice.Voicemail },
-		map[string]string{"digit": recordKey},
+		func(m i18n.Messages) string { return m.Voice.Speech.Voicemail },
+		voicemailKeywords,
 	)
 	gather1 := &twiml.VoiceGather{
 		Action:        actionStartVoicemail + "?lang=" + lang,
-		InnerElements: []twiml.Element{say1},
+		InnerElements: append(intro, say1),
 		NumDigits:     "1",
 		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherStartVoicemail),
 	}
 
-	say2 := v.sayTemplate(ctx, lang,
+	say2 := v.sayOption(ctx, lang, recordKey, speech,
 		func(m i18n.Messages) string { return m.Voice.VoicemailRepeat },
-		map[string]string{"digit": recordKey},
+		func(m i18n.Messages) string { return m.Voice.Speech.VoicemailRepeat },
+		voicemailKeywords,
 	)
 	gather2 := &twiml.VoiceGather{
 		Action:        actionStartVoicemail + "?lang=" + lang,
@@ -191,6 +844,20 @@ func (v Voice) GatherVoicemailStart(
 		Timeout:       strconv.Itoa(v.Config.Twilio.Timeouts.GatherStartVoicemail),
 	}
 
+	if callbackKey != "" && v.Config.Callbacks.Scheduled.Enabled {
+		offer := func(m i18n.Messages) string { return m.Voice.Callbacks.Offer }
+		for _, gather := range []*twiml.VoiceGather{gather1, gather2} {
+			sayOffer := v.sayTemplate(ctx, lang, offer, map[string]string{"digit": callbackKey})
+			gather.InnerElements = append(gather.InnerElements, sayOffer)
+		}
+	}
+
+	if speech {
+		hints := v.keywords(ctx, lang, voicemailKeywords)
+		v.acceptSpeech(gather1, lang, hints)
+		v.acceptSpeech(gather2, lang, hints)
+	}
+
 	return v.voice(ctx, []twiml.Element{gather1, gather2})
 }
 
@@ -202,14 +869,14 @@ func (v Voice) RecordVoicemail(
 	lang string,
 	rerecord bool,
 ) string {
-	var say *twiml.VoiceSay
+	var say twiml.Element
 	if rerecord {
 		say = v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.ReRecord })
 	} else {
 		say = v.say(ctx, lang, func(m i18n.Messages) string { return m.Voice.RecordAfterTone })
 	}
 	record := &twiml.VoiceRecord{
-		Action:      actionEndVoicemail + "?lang=" + lang,
+		Action:      withLang(actionEndVoicemail, lang),
 		FinishOnKey: recordKey,
 		Timeout:     "0",
 	}
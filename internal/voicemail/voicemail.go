@@ -0,0 +1,166 @@
+// Package voicemail keeps the voicemails left by callers, for agents to listen to by phone.
+package voicemail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/infotecho/ocomms/internal/store"
+	openapi "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+const key = "voicemails"
+
+// Voicemail is a message recorded by a caller.
+type Voicemail struct {
+	RecordingSid string    `json:"recordingSid"`
+	RecordingURL string    `json:"recordingURL"`
+	From         string    `json:"from"`
+	To           string    `json:"to"` // company DID the caller called
+	Time         time.Time `json:"time"`
+	Handled      bool      `json:"handled"` // whether an agent took care of the message
+}
+
+// RecordingsAPI is an interface for the recordings resource of [github.com/twilio/twilio-go/rest/api/v2010.ApiService].
+type RecordingsAPI interface {
+	DeleteRecording(sid string, params *openapi.DeleteRecordingParams) error
+}
+
+// Box persists voicemails, keyed by recording SID.
+type Box struct {
+	Recordings RecordingsAPI
+	Store      store.Store
+
+	mu sync.Mutex // serializes updates from concurrent calls
+}
+
+// Add keeps a new voicemail.
+func (b *Box) Add(ctx context.Context, voicemail Voicemail) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	voicemails, err := b.load(ctx)
+	if err != nil {
+		return err
+	}
+	return b.save(ctx, append(voicemails, voicemail))
+}
+
+// Get returns a voicemail by recording SID, and whether it was found.
+func (b *Box) Get(ctx context.Context, recordingSid string) (Voicemail, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	voicemails, err := b.load(ctx)
+	if err != nil {
+		return Voicemail{}, false, err
+	}
+	i := slices.IndexFunc(voicemails, func(v Voicemail) bool { return v.RecordingSid == recordingSid })
+	if i < 0 {
+		return Voicemail{}, false, nil
+	}
+	return voicemails[i], true, nil
+}
+
+// Unheard returns the voicemails no agent has handled yet, newest first.
+func (b *Box) Unheard(ctx context.Context) ([]Voicemail, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	voicemails, err := b.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	voicemails = slices.DeleteFunc(voicemails, func(v Voicemail) bool { return v.Handled })
+	slices.SortStableFunc(voicemails, func(a, b Voicemail) int { return b.Time.Compare(a.Time) })
+	return voicemails, nil
+}
+
+// Next returns the newest unheard voicemail older than current, and whether there is one.
+func (b *Box) Next(ctx context.Context, current Voicemail) (Voicemail, bool, error) {
+	unheard, err := b.Unheard(ctx)
+	if err != nil {
+		return Voicemail{}, false, err
+	}
+
+	for _, voicemail := range unheard {
+		if voicemail.Time.Before(current.Time) {
+			return voicemail, true, nil
+		}
+	}
+	return Voicemail{}, false, nil
+}
+
+// MarkHandled records that an agent took care of a voicemail.
+func (b *Box) MarkHandled(ctx context.Context, recordingSid string) error {
+	return b.update(ctx, func(voicemails []Voicemail) []Voicemail {
+		for i := range voicemails {
+			if voicemails[i].RecordingSid == recordingSid {
+				voicemails[i].Handled = true
+			}
+		}
+		return voicemails
+	})
+}
+
+// Delete removes a voicemail, along with its recording from Twilio.
+func (b *Box) Delete(ctx context.Context, recordingSid string) error {
+	err := b.update(ctx, func(voicemails []Voicemail) []Voicemail {
+		return slices.DeleteFunc(voicemails, func(v Voicemail) bool { return v.RecordingSid == recordingSid })
+	})
+	if err != nil {
+		return err
+	}
+
+	err = b.Recordings.DeleteRecording(recordingSid, &openapi.DeleteRecordingParams{})
+	if err != nil {
+		return fmt.Errorf("failed to delete voicemail recording: %w", err)
+	}
+	return nil
+}
+
+func (b *Box) update(ctx context.Context, fn func([]Voicemail) []Voicemail) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	voicemails, err := b.load(ctx)
+	if err != nil {
+		return err
+	}
+	return b.save(ctx, fn(voicemails))
+}
+
+func (b *Box) load(ctx context.Context) ([]Voicemail, error) {
+	value, ok, err := b.Store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get voicemails: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	var voicemails []Voicemail
+	err = json.Unmarshal(value, &voicemails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal voicemails: %w", err)
+	}
+	return voicemails, nil
+}
+
+func (b *Box) save(ctx context.Context, voicemails []Voicemail) error {
+	value, err := json.Marshal(voicemails)
+	if err != nil {
+		return fmt.Errorf("failed to marshal voicemails: %w", err)
+	}
+
+	err = b.Store.Set(ctx, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to set voicemails: %w", err)
+	}
+	return nil
+}
@@ -7,6 +7,18 @@ resource "google_service_account" "ocomms" {
   account_id = "ocomms"
 }
 
+resource "google_secret_manager_secret_iam_member" "ocomms_admin_token" {
+  secret_id = google_secret_manager_secret.admin_token.id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${google_service_account.ocomms.email}"
+}
+
+resource "google_secret_manager_secret_iam_member" "ocomms_twilio_account_sid" {
+  secret_id = google_secret_manager_secret.twilio_account_sid.id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${google_service_account.ocomms.email}"
+}
+
 resource "google_secret_manager_secret_iam_member" "ocomms_twilio_auth_token" {
   secret_id = google_secret_manager_secret.twilio_auth_token.id
   role      = "roles/secretmanager.secretAccessor"
@@ -24,3 +36,15 @@ resource "google_secret_manager_secret_iam_member" "ocomms-primary-agent-did" {
   role      = "roles/secretmanager.secretAccessor"
   member    = "serviceAccount:${google_service_account.ocomms.email}"
 }
+
+resource "google_secret_manager_secret_iam_member" "ocomms-company-did" {
+  secret_id = google_secret_manager_secret.company_did.id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${google_service_account.ocomms.email}"
+}
+
+resource "google_secret_manager_secret_iam_member" "ocomms-agent-pin" {
+  secret_id = google_secret_manager_secret.agent_pin.id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${google_service_account.ocomms.email}"
+}
@@ -0,0 +1,76 @@
+resource "google_project_service" "cloudscheduler" {
+  service = "cloudscheduler.googleapis.com"
+}
+
+data "google_secret_manager_secret_version" "admin_token" {
+  secret = google_secret_manager_secret.admin_token.id
+}
+
+// launches the call backs callers scheduled, since Cloud Run instances don't run in the background
+resource "google_cloud_scheduler_job" "scheduled_callbacks" {
+  depends_on = [google_project_service.cloudscheduler]
+  name       = "scheduled-callbacks"
+  schedule   = "* * * * *"
+  time_zone  = "America/Toronto"
+  region     = "northamerica-northeast1"
+
+  http_target {
+    http_method = "POST"
+    uri         = "${var.base_url}/admin/scheduled-callbacks"
+    headers = {
+      Authorization = "Bearer ${data.google_secret_manager_secret_version.admin_token.secret_data}"
+    }
+  }
+}
+
+// emails management a summary of last week's activity
+resource "google_cloud_scheduler_job" "weekly_report" {
+  depends_on = [google_project_service.cloudscheduler]
+  name       = "weekly-report"
+  schedule   = "0 8 * * 1"
+  time_zone  = "America/Toronto"
+  region     = "northamerica-northeast1"
+
+  http_target {
+    http_method = "POST"
+    uri         = "${var.base_url}/admin/reports/weekly"
+    headers = {
+      Authorization = "Bearer ${data.google_secret_manager_secret_version.admin_token.secret_data}"
+    }
+  }
+}
+
+// the same jobs for each tenant, whose admin API requests are identified by the tenant query parameter
+resource "google_cloud_scheduler_job" "tenant_scheduled_callbacks" {
+  for_each   = toset(var.tenants)
+  depends_on = [google_project_service.cloudscheduler]
+  name       = "scheduled-callbacks-${each.key}"
+  schedule   = "* * * * *"
+  time_zone  = "America/Toronto"
+  region     = "northamerica-northeast1"
+
+  http_target {
+    http_method = "POST"
+    uri         = "${var.base_url}/admin/scheduled-callbacks?tenant=${each.key}"
+    headers = {
+      Authorization = "Bearer ${data.google_secret_manager_secret_version.admin_token.secret_data}"
+    }
+  }
+}
+
+resource "google_cloud_scheduler_job" "tenant_weekly_report" {
+  for_each   = toset(var.tenants)
+  depends_on = [google_project_service.cloudscheduler]
+  name       = "weekly-report-${each.key}"
+  schedule   = "0 8 * * 1"
+  time_zone  = "America/Toronto"
+  region     = "northamerica-northeast1"
+
+  http_target {
+    http_method = "POST"
+    uri         = "${var.base_url}/admin/reports/weekly?tenant=${each.key}"
+    headers = {
+      Authorization = "Bearer ${data.google_secret_manager_secret_version.admin_token.secret_data}"
+    }
+  }
+}
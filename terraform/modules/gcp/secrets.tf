@@ -1,3 +1,10 @@
+resource "google_secret_manager_secret" "admin_token" {
+  secret_id = "admin-token"
+  replication {
+    auto {}
+  }
+}
+
 resource "google_secret_manager_secret" "sendgrid_api_key" {
   secret_id = "sendgrid-api-key"
   replication {
@@ -5,6 +12,13 @@ resource "google_secret_manager_secret" "sendgrid_api_key" {
   }
 }
 
+resource "google_secret_manager_secret" "twilio_account_sid" {
+  secret_id = "twilio-account-sid"
+  replication {
+    auto {}
+  }
+}
+
 resource "google_secret_manager_secret" "twilio_auth_token" {
   secret_id = "twilio-auth-token"
   replication {
@@ -18,3 +32,17 @@ resource "google_secret_manager_secret" "primary_agent_did" {
     auto {}
   }
 }
+
+resource "google_secret_manager_secret" "company_did" {
+  secret_id = "company-did"
+  replication {
+    auto {}
+  }
+}
+
+resource "google_secret_manager_secret" "agent_pin" {
+  secret_id = "agent-pin"
+  replication {
+    auto {}
+  }
+}
@@ -0,0 +1,18 @@
+resource "google_project_service" "storage" {
+  service = "storage.googleapis.com"
+}
+
+// keeps application state, mounted by k8s/service.yaml as the directory of the file storage driver
+resource "google_storage_bucket" "state" {
+  depends_on                  = [google_project_service.storage]
+  name                        = "ocomms-state"
+  location                    = "northamerica-northeast1"
+  uniform_bucket_level_access = true
+  public_access_prevention    = "enforced"
+}
+
+resource "google_storage_bucket_iam_member" "ocomms_state" {
+  bucket = google_storage_bucket.state.name
+  role   = "roles/storage.objectUser"
+  member = "serviceAccount:${google_service_account.ocomms.email}"
+}
@@ -1,3 +1,14 @@
 variable "github_repo_name" {
   description = "Full name of the GitHub repository to deploy source code from (format orgName/repoName)"
 }
+
+variable "base_url" {
+  description = "Public URL of the O-Comms service, as in BASE_URL of k8s/service.yaml"
+  default     = "https://ocomms-539601029037.northamerica-northeast1.run.app"
+}
+
+variable "tenants" {
+  description = "IDs of the tenants in config.yaml without their own admin token, to schedule their admin API jobs with the host's"
+  type        = list(string)
+  default     = []
+}